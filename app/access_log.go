@@ -0,0 +1,40 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mmiftahrzki/customer/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// accessLog writes one entry per request once it has been served. It must
+// run inside requestId so the entry carries the request id and the user
+// VerifyJWT added to the request-scoped logger.
+func accessLog(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := newStatusRecorder(w)
+
+		next.ServeHTTP(recorder, r)
+
+		entry := logger.FromContext(r.Context())
+
+		user, ok := entry.Data["user"]
+		if !ok {
+			user = "anonymous"
+		}
+
+		entry.WithFields(logrus.Fields{
+			"component":   "access",
+			"method":      r.Method,
+			"route":       routeLabel(r),
+			"path":        r.URL.Path,
+			"status":      recorder.Status(),
+			"bytes":       recorder.bytes,
+			"latency_ms":  float64(time.Since(start).Microseconds()) / 1000,
+			"user":        user,
+			"remote_addr": r.RemoteAddr,
+		}).Info("request served")
+	}
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mmiftahrzki/customer/logger"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs records what the root logger writes for the rest of the test,
+// in place of its usual hooks.
+func captureLogs(t *testing.T) *test.Hook {
+	previous := logger.GetLogger().ReplaceHooks(logrus.LevelHooks{})
+	hook := test.NewLocal(logger.GetLogger())
+	t.Cleanup(func() { logger.GetLogger().ReplaceHooks(previous) })
+
+	return hook
+}
+
+func TestAccessLog(t *testing.T) {
+	logs := captureLogs(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/customer/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logger.AddFields(r.Context(), logrus.Fields{"user": "mary@example.com"})
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+	handler := pipe(withRoute, requestId, accessLog)(recordRoute(mux.ServeHTTP))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/customer/7", nil)
+	r.Header.Set(RequestIdHeader, "abc-123")
+	handler(httptest.NewRecorder(), r)
+
+	entry := logs.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "request served", entry.Message)
+	assert.Equal(t, "access", entry.Data["component"])
+	assert.Equal(t, "abc-123", entry.Data["request_id"])
+	assert.Equal(t, http.MethodGet, entry.Data["method"])
+	assert.Equal(t, "/api/customer/{id}", entry.Data["route"])
+	assert.Equal(t, "/api/customer/7", entry.Data["path"])
+	assert.Equal(t, http.StatusCreated, entry.Data["status"])
+	assert.Equal(t, 5, entry.Data["bytes"])
+	assert.Equal(t, "mary@example.com", entry.Data["user"])
+	assert.IsType(t, float64(0), entry.Data["latency_ms"])
+	assert.GreaterOrEqual(t, entry.Data["latency_ms"], float64(0))
+
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	entry = logs.LastEntry()
+	assert.Equal(t, http.StatusNotFound, entry.Data["status"])
+	assert.Equal(t, unmatchedRoute, entry.Data["route"])
+	assert.Equal(t, "anonymous", entry.Data["user"])
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mmiftahrzki/customer/metrics"
+)
+
+var httpRequests = metrics.GetRegistry().NewCounterVec("http_requests_total",
+	"HTTP requests served, by method, route pattern and status code.", "method", "route", "status")
+
+var httpRequestDuration = metrics.GetRegistry().NewHistogramVec("http_request_duration_seconds",
+	"HTTP request latency in seconds, by method and route pattern.", metrics.DefaultBuckets, "method", "route")
+
+// otherMethod labels requests with a method outside the standard ones, so
+// clients cannot create new series by making methods up.
+const otherMethod string = "OTHER"
+
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return otherMethod
+	}
+}
+
+// instrument records a request count and latency for every request, labelled
+// by the route recorded by recordRoute.
+func instrument(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := newStatusRecorder(w)
+
+		next.ServeHTTP(recorder, r)
+
+		method, route := methodLabel(r.Method), routeLabel(r)
+		httpRequests.Inc(method, route, strconv.Itoa(recorder.Status()))
+		httpRequestDuration.Observe(time.Since(start).Seconds(), method, route)
+	}
+}
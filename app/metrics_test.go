@@ -0,0 +1,35 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mmiftahrzki/customer/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMethodLabel(t *testing.T) {
+	assert.Equal(t, http.MethodGet, methodLabel(http.MethodGet))
+	assert.Equal(t, http.MethodPatch, methodLabel(http.MethodPatch))
+	assert.Equal(t, otherMethod, methodLabel("get"))
+	assert.Equal(t, otherMethod, methodLabel("BREW"))
+}
+
+func TestInstrumentBoundsMethods(t *testing.T) {
+	handler := withRoute(instrument(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+
+	for _, method := range []string{"BREW", "X-RANDOM-1", "X-RANDOM-2"} {
+		handler(httptest.NewRecorder(), httptest.NewRequest(method, "/", nil))
+	}
+
+	var exposition bytes.Buffer
+	metrics.GetRegistry().Write(&exposition)
+
+	assert.Contains(t, exposition.String(), `http_requests_total{method="OTHER",route="unmatched",status="405"} 3`)
+	assert.NotContains(t, exposition.String(), "BREW")
+	assert.NotContains(t, exposition.String(), "X-RANDOM")
+}
@@ -1,58 +1,140 @@
 package app
 
 import (
-	"crypto/rand"
-	"database/sql"
-	"encoding/base64"
-	"fmt"
 	"net/http"
 
 	"github.com/mmiftahrzki/customer/auth"
+	"github.com/mmiftahrzki/customer/config"
+	"github.com/mmiftahrzki/customer/cors"
 	"github.com/mmiftahrzki/customer/customer"
+	"github.com/mmiftahrzki/customer/database"
 	"github.com/mmiftahrzki/customer/docs"
+	"github.com/mmiftahrzki/customer/encryption"
+	"github.com/mmiftahrzki/customer/health"
+	"github.com/mmiftahrzki/customer/logger"
+	"github.com/mmiftahrzki/customer/metrics"
+	"github.com/mmiftahrzki/customer/ratelimit"
 )
 
-func newMux(db *sql.DB) *http.ServeMux {
-	jwtSigningKey := make([]byte, 256)
-	rand.Read(jwtSigningKey[:])
-	base64Encoded := base64.StdEncoding.EncodeToString(jwtSigningKey)
-	fmt.Println(base64Encoded)
-
+func newMux(cfg config.Config, db *database.DB, fields *encryption.Fields, health health.Health) http.Handler {
 	appHandler := handler{}
+	adminRole := auth.RoleAdmin
 
-	auth := auth.New(jwtSigningKey)
-	customer := customer.New(db)
-	doc := docs.New()
-
+	auth := auth.New([]byte(cfg.Auth.JWTSecretKey), []byte(cfg.Auth.AdminKey))
+	auth.SetAdminEmails(cfg.Auth.AdminEmails)
+	customer := customer.New(db, fields, cfg.Customer)
+	customer.SetPageSize(cfg.Customer.PageSize)
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.ClientKey(cfg.RateLimit.APIKeyHeader))
+	limiter.SetPolicies(rateLimitPolicies(cfg.RateLimit))
 	customerMux := http.NewServeMux()
 	mux := http.NewServeMux()
+	sharing := cors.New(func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		if pattern == "/api/customer/" {
+			_, pattern = customerMux.Handler(r)
+		}
+
+		return pattern
+	})
+	sharing.SetPolicy(corsPolicy(cfg.CORS))
+	config.Subscribe(func(previous config.Config, next config.Config) {
+		auth.SetAdminEmails(next.Auth.AdminEmails)
+
+		if next.Customer.PageSize != previous.Customer.PageSize {
+			customer.SetPageSize(next.Customer.PageSize)
+		}
+
+		if next.Database.SlowQueryThreshold != previous.Database.SlowQueryThreshold {
+			database.SetSlowQueryThreshold(next.Database.SlowQueryThreshold)
+		}
+
+		limiter.SetPolicies(rateLimitPolicies(next.RateLimit))
+		sharing.SetPolicy(corsPolicy(next.CORS))
+	})
+	doc := docs.New()
+	logLevel := logger.NewHandler()
+	queryStats := database.NewStatsHandler()
 
+	health.Register("database", db.PingContext)
+	health.Register("migrations", database.CheckMigrations(db))
+	health.Register("signing_keys", auth.CheckSigningKey)
+
+	api := pipe(limiter.Middleware, requestTimeout(cfg.App.RequestTimeout, cfg.App.MaxRequestTimeout))
 	testThenVerifyAuth := pipe(Test, auth.Middleware.VerifyJWT)
 	deleteSingleById := add(auth.Middleware.VerifyJWT, customer.Handler.DeleteSingleById)
 	postSingle := add(auth.Middleware.VerifyJWT, customer.Handler.PostSingle)
+	postBatch := add(auth.Middleware.VerifyJWT, customer.Handler.PostBatch)
+	getDuplicatesById := add(auth.Middleware.VerifyJWT, customer.Handler.GetDuplicatesById)
+	adminOnly := pipe(auth.Middleware.VerifyJWT, auth.Middleware.RequireRole(adminRole))
+	postMerge := adminOnly(customer.Handler.PostMerge)
+	getExport := adminOnly(customer.Handler.GetExport)
+	getPersonalDataById := adminOnly(customer.Handler.GetPersonalDataById)
+	postEraseById := adminOnly(customer.Handler.PostEraseById)
+	getLogLevel := adminOnly(logLevel.GetLevel)
+	putLogLevel := adminOnly(logLevel.PutLevel)
+	getQueryStats := adminOnly(queryStats.GetQueryStats)
+	deleteQueryStats := adminOnly(queryStats.DeleteQueryStats)
 	putSingleById := add(auth.Middleware.VerifyJWT, customer.Handler.PutSingleById)
 	getSingleAndUpdateAddressById := add(auth.Middleware.VerifyJWT, customer.Handler.GetSingleAndUpdateAddressById)
 
-	customerMux.HandleFunc("GET /api/customer/{$}", customer.Handler.GetMultiple)
-	customerMux.HandleFunc("GET /api/customer/{id}", customer.Handler.GetSingleById)
-	customerMux.HandleFunc("GET /api/customer/{id}/prev/{$}", customer.Handler.GetMultiplePrev)
-	customerMux.HandleFunc("GET /api/customer/{id}/next/{$}", customer.Handler.GetMultipleNext)
-	customerMux.HandleFunc("POST /api/customer/{$}", postSingle)
-	customerMux.HandleFunc("PUT /api/customer/{id}", putSingleById)
-	customerMux.HandleFunc("PATCH /api/customer/{customer_id}/address/{address_id}", getSingleAndUpdateAddressById)
-	customerMux.HandleFunc("DELETE /api/customer/{id}", deleteSingleById)
+	customerMux.HandleFunc("GET /api/customer/{$}", api(customer.Handler.GetMultiple))
+	// exports stream for as long as the client keeps reading, so they skip the
+	// request timeout; the client going away still ends them.
+	customerMux.HandleFunc("GET /api/customer/export", limiter.Middleware(getExport))
+	customerMux.HandleFunc("GET /api/customer/{id}", api(customer.Handler.GetSingleById))
+	customerMux.HandleFunc("GET /api/customer/{id}/prev/{$}", api(customer.Handler.GetMultiplePrev))
+	customerMux.HandleFunc("GET /api/customer/{id}/next/{$}", api(customer.Handler.GetMultipleNext))
+	customerMux.HandleFunc("GET /api/customer/{id}/duplicates", api(getDuplicatesById))
+	customerMux.HandleFunc("GET /api/customer/{id}/personal-data", api(getPersonalDataById))
+	customerMux.HandleFunc("POST /api/customer/{$}", api(postSingle))
+	customerMux.HandleFunc("POST /api/customer/batch", api(postBatch))
+	customerMux.HandleFunc("POST /api/customer/merge", api(postMerge))
+	customerMux.HandleFunc("POST /api/customer/{id}/erase", api(postEraseById))
+	customerMux.HandleFunc("PUT /api/customer/{id}", api(putSingleById))
+	customerMux.HandleFunc("PATCH /api/customer/{customer_id}/address/{address_id}", api(getSingleAndUpdateAddressById))
+	customerMux.HandleFunc("DELETE /api/customer/{id}", api(deleteSingleById))
 
 	mux.Handle("GET /{$}", appHandler)
+	mux.HandleFunc("GET /healthz", health.Handler.Liveness)
+	mux.HandleFunc("GET /readyz", health.Handler.Readiness)
+	mux.Handle("GET /metrics", metrics.Handler(metrics.GetRegistry()))
 	mux.HandleFunc("GET /swagger-css", doc.Handler.SwaggerCSS)
 	mux.HandleFunc("GET /swagger-js", doc.Handler.SwaggerJS)
 	mux.HandleFunc("GET /swagger", doc.Handler.SwaggerJson)
 	mux.HandleFunc("GET /restful-api", doc.Handler.Swagger)
 
-	mux.HandleFunc("POST /api/test", testThenVerifyAuth(customer.Handler.GetMultiple))
+	mux.HandleFunc("POST /api/test", api(testThenVerifyAuth(customer.Handler.GetMultiple)))
+
+	mux.HandleFunc("POST /api/auth/{$}", api(auth.Handler.CreateAuthToken))
 
-	mux.HandleFunc("POST /api/auth/{$}", auth.Handler.CreateAuthToken)
+	mux.HandleFunc("GET /admin/log-level", getLogLevel)
+	mux.HandleFunc("PUT /admin/log-level", putLogLevel)
+	mux.HandleFunc("GET /admin/query-stats", getQueryStats)
+	mux.HandleFunc("DELETE /admin/query-stats", deleteQueryStats)
 
 	mux.HandleFunc("/api/customer/", customerMux.ServeHTTP)
 
-	return mux
+	return pipe(withRoute, instrument, traceRequests, requestId, accessLog, sharing.Middleware, readYourWrites)(recordRoute(mux.ServeHTTP))
+}
+
+// rateLimitPolicies converts the configured rate limits to the limiter's.
+func rateLimitPolicies(cfg config.RateLimitConfig) (ratelimit.Policy, map[string]ratelimit.Policy) {
+	routes := make(map[string]ratelimit.Policy, len(cfg.Routes))
+	for pattern, policy := range cfg.Routes {
+		routes[pattern] = ratelimit.Policy{Requests: policy.Requests, Period: policy.Period, Burst: policy.Burst}
+	}
+
+	return ratelimit.Policy{Requests: cfg.Requests, Period: cfg.Period, Burst: cfg.Burst}, routes
+}
+
+// corsPolicy converts the configured CORS settings to the middleware's.
+func corsPolicy(cfg config.CORSConfig) cors.Policy {
+	return cors.Policy{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	}
 }
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmiftahrzki/customer/config"
+	"github.com/mmiftahrzki/customer/database"
+	"github.com/mmiftahrzki/customer/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAdminEmail string = "admin@example.com"
+const testAdminKey string = "fedcba9876543210fedcba9876543210"
+
+// testConfig is the config newTestMux serves the app's routes with.
+func testConfig() config.Config {
+	return config.Config{
+		Auth:      config.AuthConfig{JWTSecretKey: "0123456789abcdef0123456789abcdef", AdminKey: testAdminKey, AdminEmails: []string{testAdminEmail}},
+		Customer:  config.CustomerConfig{PageSize: 25},
+		RateLimit: config.RateLimitConfig{Requests: 1000, Period: time.Minute, Burst: 1000},
+		App:       config.AppConfig{RequestTimeout: 5 * time.Second, MaxRequestTimeout: 5 * time.Second},
+	}
+}
+
+// newTestMux returns the app's routes configured by cfg over a freshly
+// migrated SQLite database.
+func newTestMux(t *testing.T, cfg config.Config) http.Handler {
+	db, err := database.Open(context.Background(), config.DatabaseConfig{
+		Driver:          database.DriverSQLite,
+		Name:            filepath.Join(t.TempDir(), "customer.db"),
+		MaxConnection:   4,
+		ConnectAttempts: 1,
+		ConnectTimeout:  5 * time.Second,
+		WriteTimeout:    5 * time.Second,
+		Loc:             "UTC",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, database.Migrate(context.Background(), db))
+
+	return newMux(cfg, db, nil, health.New(time.Second))
+}
+
+// request serves method target with body on mux, sending token as a bearer
+// token when set.
+func request(mux http.Handler, method string, target string, body string, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	return w
+}
+
+// token asks mux for a token for body.
+func token(t *testing.T, mux http.Handler, body string) string {
+	w := request(mux, http.MethodPost, "/api/auth/", body, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var res struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	return res.Token
+}
+
+func TestMuxMergeIsAdminOnly(t *testing.T) {
+	mux := newTestMux(t, testConfig())
+	user := token(t, mux, `{"email": "owner@example.com"}`)
+	adminEmail := token(t, mux, `{"email": "`+testAdminEmail+`"}`)
+	admin := token(t, mux, `{"email": "`+testAdminEmail+`", "admin_key": "`+testAdminKey+`"}`)
+
+	for _, body := range []string{
+		`{"first_name": "Mary", "last_name": "Smith", "email": "mary@example.com"}`,
+		`{"first_name": "Mary", "last_name": "Smith", "email": "mary.smith@example.com"}`,
+	} {
+		w := request(mux, http.MethodPost, "/api/customer/", body, user)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	merge := `{"survivor_id": 1, "duplicate_id": 2}`
+
+	w := request(mux, http.MethodPost, "/api/customer/merge", merge, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code, "a missing token is rejected")
+
+	w = request(mux, http.MethodPost, "/api/customer/merge", merge, user)
+	assert.Equal(t, http.StatusForbidden, w.Code, "even the owner of both customers needs the admin role")
+
+	w = request(mux, http.MethodPost, "/api/customer/merge", merge, adminEmail)
+	assert.Equal(t, http.StatusForbidden, w.Code, "a token minted for an admin email without the key is not admin")
+
+	w = request(mux, http.MethodGet, "/api/customer/2", "", "")
+	assert.Equal(t, http.StatusOK, w.Code, "a rejected merge must leave the duplicate alone")
+
+	w = request(mux, http.MethodPost, "/api/customer/merge", merge, admin)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestMuxRateLimitIgnoresSubject(t *testing.T) {
+	cfg := testConfig()
+	cfg.RateLimit = config.RateLimitConfig{
+		Requests: 2,
+		Period:   time.Hour,
+		Routes:   map[string]config.RateLimitPolicy{"POST /api/auth/{$}": {Requests: 100, Period: time.Hour}},
+	}
+	mux := newTestMux(t, cfg)
+
+	statuses := []int{}
+	for i := range 3 {
+		rotated := token(t, mux, fmt.Sprintf(`{"email": "client%d@example.com"}`, i))
+
+		statuses = append(statuses, request(mux, http.MethodGet, "/api/customer/", "", rotated).Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, statuses, "a new email per request must not buy a new bucket")
+}
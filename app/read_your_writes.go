@@ -0,0 +1,15 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/mmiftahrzki/customer/database"
+)
+
+// readYourWrites starts a database session per request, so reads made after
+// a write in the same request go to the primary instead of a replica.
+func readYourWrites(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(database.NewSession(r.Context())))
+	}
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/mmiftahrzki/customer/logger"
+	"github.com/mmiftahrzki/customer/responses"
+	"github.com/mmiftahrzki/customer/tracing"
+)
+
+const RequestIdHeader string = responses.RequestIdHeader
+
+const maxRequestIdLength int = 128
+
+// validRequestId accepts ids a client or proxy may reasonably send while
+// keeping control characters and separators out of logs and headers.
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLength {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '/', c == '=':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestId() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
+// requestId reuses the X-Request-ID of the request, or generates one,
+// echoes it on the response and stores a logger carrying it in the context
+// for the handler, service and repo to log through.
+func requestId(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIdHeader)
+		if !validRequestId(id) {
+			id = newRequestId()
+		}
+
+		w.Header().Set(RequestIdHeader, id)
+		tracing.SpanFromContext(r.Context()).SetAttribute("http.request_id", id)
+
+		entry := logger.GetLogger().WithField("request_id", id)
+		ctx := logger.NewContext(r.Context(), entry)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
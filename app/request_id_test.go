@@ -0,0 +1,51 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mmiftahrzki/customer/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestId(t *testing.T) {
+	var contextId any
+	handler := requestId(func(w http.ResponseWriter, r *http.Request) {
+		contextId = logger.FromContext(r.Context()).Data["request_id"]
+	})
+
+	serve := func(incoming string) string {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if incoming != "" {
+			r.Header.Set(RequestIdHeader, incoming)
+		}
+
+		w := httptest.NewRecorder()
+		handler(w, r)
+
+		id := w.Header().Get(RequestIdHeader)
+		assert.Equal(t, id, contextId, "the logger should carry the id echoed on the response")
+
+		return id
+	}
+
+	for _, id := range []string{"abc-123", "trace:1/2+3=4_5.6", strings.Repeat("a", maxRequestIdLength)} {
+		assert.Equal(t, id, serve(id), "a valid id is kept")
+	}
+
+	for name, id := range map[string]string{
+		"missing":   "",
+		"oversized": strings.Repeat("a", maxRequestIdLength+1),
+		"space":     "abc 123",
+		"newline":   "abc\n123",
+		"non-ascii": "abc-ü",
+	} {
+		replaced := serve(id)
+		assert.NotEqual(t, id, replaced, name)
+		assert.Regexp(t, `^[0-9a-f]{32}$`, replaced, name)
+	}
+
+	assert.NotEqual(t, serve(""), serve(""), "generated ids should be unique")
+}
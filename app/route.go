@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const unmatchedRoute string = "unmatched"
+
+type routeContextKey struct{}
+
+// route holds the pattern the mux matched. Middleware may hand the mux a
+// copy of the request made by WithContext, so the pattern is recorded here,
+// shared by every copy, instead of being read back from r.Pattern.
+type route struct {
+	pattern string
+}
+
+// withRoute must be the outermost middleware; it makes the matched pattern
+// available to every middleware inside it once the request has been served.
+func withRoute(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(routeContextKey{}).(*route); !ok {
+			r = r.WithContext(context.WithValue(r.Context(), routeContextKey{}, &route{}))
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// recordRoute must directly wrap the mux, which sets r.Pattern on the
+// request it is handed, nested muxes included.
+func recordRoute(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		if matched, ok := r.Context().Value(routeContextKey{}).(*route); ok {
+			matched.pattern = r.Pattern
+		}
+	}
+}
+
+// routeLabel returns the pattern the mux matched for r without its method,
+// so /api/customer/1 and /api/customer/2 share "/api/customer/{id}".
+// Requests no route matched are grouped under a single label to keep the
+// number of distinct values bounded.
+func routeLabel(r *http.Request) string {
+	pattern := r.Pattern
+	if matched, ok := r.Context().Value(routeContextKey{}).(*route); ok {
+		pattern = matched.pattern
+	}
+
+	if pattern == "" {
+		return unmatchedRoute
+	}
+
+	if _, path, found := strings.Cut(pattern, " "); found {
+		return path
+	}
+
+	return pattern
+}
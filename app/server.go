@@ -1,37 +1,163 @@
 package app
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/mmiftahrzki/customer/config"
+	"github.com/mmiftahrzki/customer/database"
+	"github.com/mmiftahrzki/customer/encryption"
+	"github.com/mmiftahrzki/customer/health"
 	"github.com/mmiftahrzki/customer/logger"
 	"github.com/sirupsen/logrus"
 )
 
+const defaultShutdownTimeout time.Duration = 15 * time.Second
+
+// ErrForcedShutdown is returned by Run and Shutdown when in-flight requests
+// or background workers did not finish within the drain timeout.
+var ErrForcedShutdown = errors.New("app: drain timeout exceeded, remaining work was dropped")
+
 type app struct {
-	server *http.Server
-	log    *logrus.Entry
+	server          *http.Server
+	drain           func()
+	drainDelay      time.Duration
+	shutdownTimeout time.Duration
+	workerCtx       context.Context
+	stopWorkers     context.CancelFunc
+	workers         sync.WaitGroup
+	log             *logrus.Entry
 }
 
-func New(cfg config.AppConfig, db *sql.DB) *app {
+func New(cfg config.Config, db *database.DB, fields *encryption.Fields) *app {
 	app_logger := logger.GetLogger().WithField("component", "app")
+	health := health.New(cfg.App.HealthCheckTimeout)
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+
+	shutdownTimeout := cfg.App.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
 
 	return &app{
-		log: app_logger,
+		log:             app_logger,
+		drain:           health.Drain,
+		drainDelay:      cfg.App.DrainDelay,
+		shutdownTimeout: shutdownTimeout,
+		workerCtx:       workerCtx,
+		stopWorkers:     stopWorkers,
 		server: &http.Server{
-			Addr:         fmt.Sprintf(":%d", cfg.Port),
-			Handler:      newMux(db),
+			Addr:         fmt.Sprintf(":%d", cfg.App.Port),
+			Handler:      newMux(cfg, db, fields, health),
 			WriteTimeout: time.Second * 30,
 			ReadTimeout:  time.Second * 10,
 		},
 	}
 }
 
-func (a *app) Run() error {
-	a.log.Infof("Listening on %s", a.server.Addr)
+// Go runs fn in the background until the app shuts down. fn must return
+// soon after its context is cancelled; shutdown waits for it.
+func (a *app) Go(fn func(ctx context.Context)) {
+	a.workers.Add(1)
+
+	go func() {
+		defer a.workers.Done()
+
+		fn(a.workerCtx)
+	}()
+}
+
+// Run serves HTTP until ctx is cancelled, typically by SIGINT or SIGTERM,
+// and then shuts down gracefully within the configured drain delay and
+// timeout.
+func (a *app) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		a.stopWorkers()
+		a.workers.Wait()
+
+		return err
+	}
+
+	return a.serve(ctx, listener)
+}
+
+// serve is Run on a listener already open.
+func (a *app) serve(ctx context.Context, listener net.Listener) error {
+	serverErr := make(chan error, 1)
+
+	go func() {
+		a.log.Infof("Listening on %s", listener.Addr())
+
+		serverErr <- a.server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serverErr:
+		a.stopWorkers()
+		a.workers.Wait()
+
+		return err
+	case <-ctx.Done():
+	}
+
+	a.log.Infof("shutting down, failing readiness for %s then draining for up to %s", a.drainDelay, a.shutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.drainDelay+a.shutdownTimeout)
+	defer cancel()
+
+	return a.Shutdown(shutdownCtx)
+}
+
+// Shutdown fails readiness first and keeps serving for the drain delay, so
+// load balancers notice and stop routing new traffic here, then stops
+// accepting connections and waits for in-flight requests and background
+// workers until ctx is done. Whatever is still running by then is dropped
+// and ErrForcedShutdown is returned.
+func (a *app) Shutdown(ctx context.Context) error {
+	forced := false
+
+	a.drain()
+
+	select {
+	case <-time.After(a.drainDelay):
+	case <-ctx.Done():
+	}
+
+	err := a.server.Shutdown(ctx)
+	if err != nil {
+		a.log.Warnf("in-flight requests did not finish: %v", err)
+
+		a.server.Close()
+		forced = true
+	}
+
+	a.stopWorkers()
+
+	done := make(chan struct{})
+	go func() {
+		a.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		a.log.Warn("background workers did not stop in time")
+
+		forced = true
+	}
+
+	if forced {
+		return ErrForcedShutdown
+	}
+
+	a.log.Info("shutdown complete")
 
-	return a.server.ListenAndServe()
+	return nil
 }
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mmiftahrzki/customer/health"
+	"github.com/mmiftahrzki/customer/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestApp returns an app serving handler on a local listener, with
+// readiness served at /readyz.
+func newTestApp(t *testing.T, handler http.HandlerFunc, drainDelay time.Duration, shutdownTimeout time.Duration) (*app, net.Listener) {
+	health := health.New(time.Second)
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /readyz", health.Handler.Readiness)
+	mux.HandleFunc("/", handler)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	return &app{
+		log:             logger.GetLogger().WithField("component", "app"),
+		drain:           health.Drain,
+		drainDelay:      drainDelay,
+		shutdownTimeout: shutdownTimeout,
+		workerCtx:       workerCtx,
+		stopWorkers:     stopWorkers,
+		server:          &http.Server{Addr: listener.Addr().String(), Handler: mux},
+	}, listener
+}
+
+func get(t *testing.T, listener net.Listener, path string) int {
+	res, err := http.Get("http://" + listener.Addr().String() + path)
+	require.NoError(t, err)
+	res.Body.Close()
+
+	return res.StatusCode
+}
+
+func TestShutdownDrainDelay(t *testing.T) {
+	a, listener := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {}, 500*time.Millisecond, time.Second)
+	go a.server.Serve(listener)
+
+	assert.Equal(t, http.StatusOK, get(t, listener, "/readyz"))
+
+	start := time.Now()
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- a.Shutdown(context.Background())
+	}()
+
+	assert.Eventually(t, func() bool {
+		return get(t, listener, "/readyz") == http.StatusServiceUnavailable
+	}, 300*time.Millisecond, 10*time.Millisecond, "readiness should fail while requests are still served")
+	assert.Equal(t, http.StatusOK, get(t, listener, "/"))
+
+	assert.NoError(t, <-shutdown)
+	assert.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond, "connections should only close after the drain delay")
+}
+
+// blockingHandler signals on started once a request is in flight and holds
+// it until release is closed.
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func TestRunCleanShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	a, listener := newTestApp(t, blockingHandler(started, release), 0, 5*time.Second)
+
+	workerStopped := make(chan struct{})
+	a.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		close(workerStopped)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := make(chan error, 1)
+	go func() {
+		run <- a.serve(ctx, listener)
+	}()
+
+	status := make(chan int, 1)
+	go func() {
+		status <- get(t, listener, "/slow")
+	}()
+
+	<-started
+	cancel()
+
+	time.AfterFunc(100*time.Millisecond, func() { close(release) })
+
+	assert.Equal(t, http.StatusOK, <-status, "the in-flight request should finish")
+	assert.NoError(t, <-run)
+
+	select {
+	case <-workerStopped:
+	default:
+		t.Error("background workers should be stopped")
+	}
+}
+
+func TestRunForcedShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+
+	a, listener := newTestApp(t, blockingHandler(started, release), 0, 100*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := make(chan error, 1)
+	go func() {
+		run <- a.serve(ctx, listener)
+	}()
+
+	go http.Get("http://" + listener.Addr().String() + "/stuck")
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-run:
+		assert.ErrorIs(t, err, ErrForcedShutdown)
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown should give up after the drain timeout")
+	}
+}
+
+func TestRunForcedByWorker(t *testing.T) {
+	a, listener := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {}, 0, 100*time.Millisecond)
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+	a.Go(func(ctx context.Context) {
+		<-stuck
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, a.serve(ctx, listener), ErrForcedShutdown)
+}
+
+func TestRunListenError(t *testing.T) {
+	a, listener := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {}, 0, time.Second)
+	defer listener.Close()
+
+	a.server.Addr = listener.Addr().String()
+
+	err := a.Run(context.Background())
+	assert.Error(t, err, "the address is taken")
+	assert.False(t, errors.Is(err, ErrForcedShutdown))
+}
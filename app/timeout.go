@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mmiftahrzki/customer/responses"
+)
+
+const RequestTimeoutHeader string = "X-Request-Timeout"
+
+var errInvalidTimeout = errors.New("invalid timeout duration value")
+
+// parseTimeout reads a positive duration such as "1.5s" or, as the timeout
+// query parameter always took, a number of milliseconds.
+func parseTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		ms, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, errInvalidTimeout
+		}
+
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+
+	if timeout <= 0 {
+		return 0, errInvalidTimeout
+	}
+
+	return timeout, nil
+}
+
+// requestTimeout gives each request a deadline of defaultTimeout, or of the
+// timeout the client asked for in the X-Request-Timeout header or the
+// timeout query parameter, capped at maxTimeout. Zero maxTimeout ignores
+// what clients ask for; zero defaultTimeout leaves other requests without a
+// deadline.
+//
+// Handlers pass the deadline on through the request context, so the service
+// and repo give up once it passes. A handler that then returns without
+// answering is answered with a 504 here.
+func requestTimeout(defaultTimeout time.Duration, maxTimeout time.Duration) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			timeout := defaultTimeout
+
+			requested := r.Header.Get(RequestTimeoutHeader)
+			if requested == "" {
+				requested = r.URL.Query().Get("timeout")
+			}
+
+			if requested != "" && maxTimeout > 0 {
+				clientTimeout, err := parseTimeout(requested)
+				if err != nil {
+					responses.Error(w, r, http.StatusUnprocessableEntity, err.Error())
+
+					return
+				}
+
+				timeout = min(clientTimeout, maxTimeout)
+			}
+
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			recorder := newStatusRecorder(w)
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			if recorder.status == 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				responses.Error(w, r, http.StatusGatewayTimeout, fmt.Sprintf("request timed out after %s", timeout))
+			}
+		}
+	}
+}
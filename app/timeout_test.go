@@ -0,0 +1,78 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimeout(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"1.5s":  1500 * time.Millisecond,
+		"250ms": 250 * time.Millisecond,
+		"2000":  2 * time.Second,
+	} {
+		timeout, err := parseTimeout(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, want, timeout, value)
+	}
+
+	for _, value := range []string{"soon", "0", "-1s"} {
+		_, err := parseTimeout(value)
+		assert.ErrorIs(t, err, errInvalidTimeout, value)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	var remaining time.Duration
+	handler := requestTimeout(time.Second, 5*time.Second)(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		if !ok {
+			w.WriteHeader(http.StatusOK)
+
+			return
+		}
+
+		remaining = time.Until(deadline)
+		if r.URL.Query().Get("block") != "" {
+			<-r.Context().Done()
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serve := func(target string, header string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		if header != "" {
+			r.Header.Set(RequestTimeoutHeader, header)
+		}
+
+		w := httptest.NewRecorder()
+		handler(w, r)
+
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/", "").Code)
+	assert.InDelta(t, time.Second, remaining, float64(100*time.Millisecond), "the server default")
+
+	serve("/?timeout=3000", "")
+	assert.InDelta(t, 3*time.Second, remaining, float64(100*time.Millisecond), "from the query")
+
+	serve("/?timeout=3000", "2s")
+	assert.InDelta(t, 2*time.Second, remaining, float64(100*time.Millisecond), "the header beats the query")
+
+	serve("/", "1m")
+	assert.InDelta(t, 5*time.Second, remaining, float64(100*time.Millisecond), "capped")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, serve("/", "soon").Code)
+
+	w := serve("/?block=1", "20ms")
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Contains(t, w.Body.String(), "request timed out after 20ms")
+}
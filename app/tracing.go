@@ -0,0 +1,40 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mmiftahrzki/customer/tracing"
+)
+
+// traceRequests starts a server span per request, continuing the trace of
+// an incoming W3C traceparent header when there is one. The span is named
+// after the matched route once the request has been served.
+func traceRequests(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if parent, ok := tracing.ParseTraceparent(r.Header.Get("traceparent")); ok {
+			ctx = tracing.ContextWithRemoteParent(ctx, parent)
+		}
+
+		ctx, span := tracing.Start(ctx, "HTTP "+r.Method, tracing.WithKind(tracing.KindServer))
+		defer span.End()
+
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.Path)
+		span.SetAttribute("net.peer.addr", r.RemoteAddr)
+
+		recorder := newStatusRecorder(w)
+
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		route := routeLabel(r)
+		span.SetName(r.Method + " " + route)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.status_code", recorder.Status())
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("%d %s", recorder.Status(), http.StatusText(recorder.Status())))
+		}
+	}
+}
@@ -1,16 +1,54 @@
 package auth
 
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
 type auth struct {
 	Handler    handler
 	Middleware middleware
+	service    service
 }
 
-func New(signingKey []byte) auth {
-	service := newService(signingKey)
+var errSigningKeyMissing = errors.New("auth: jwt signing key is not loaded")
+
+// New returns the auth component signing tokens with signingKey. adminKey is
+// the secret a token request must carry to be issued the admin role; when
+// empty no one is.
+func New(signingKey []byte, adminKey []byte) auth {
+	service := newService(signingKey, adminKey)
 	handler := newHandler(service)
 
 	return auth{
 		Middleware: newMiddleware(service),
 		Handler:    handler,
+		service:    service,
+	}
+}
+
+// SetAdminEmails replaces the emails that may be issued tokens with the
+// admin role. Tokens already issued keep their role until they expire.
+func (a auth) SetAdminEmails(emails []string) {
+	a.service.setAdminEmails(emails)
+}
+
+// CheckSigningKey is a readiness check failing unless a probe token can be
+// both signed and verified with the loaded JWT signing key.
+func (a auth) CheckSigningKey(ctx context.Context) error {
+	if len(a.service.signingKey) == 0 {
+		return errSigningKeyMissing
+	}
+
+	token, err := a.service.generateJWT(ModelCreate{Email: "readiness-probe"})
+	if err != nil {
+		return fmt.Errorf("auth: signing a probe token: %w", err)
 	}
+
+	if _, err = a.service.getToken(token); err != nil {
+		return fmt.Errorf("auth: verifying a probe token: %w", err)
+	}
+
+	return nil
 }
@@ -2,14 +2,17 @@ package auth
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var mux http.Handler
@@ -72,14 +75,51 @@ func NewTestScenarioWithInput[T any, U any](input T, statusCode int, expectedDat
 	}
 }
 
+const testAdminEmail string = "admin@example.com"
+const testAdminKey string = "fedcba9876543210fedcba9876543210"
+
+var testAuth auth
+
 func init() {
-	mux = New().Handler
+	testAuth = New([]byte("0123456789abcdef0123456789abcdef"), []byte(testAdminKey))
+	testAuth.SetAdminEmails([]string{testAdminEmail})
+
+	adminOnly := testAuth.Middleware.VerifyJWT(testAuth.Middleware.RequireRole(RoleAdmin)(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	testMux := http.NewServeMux()
+	testMux.HandleFunc("POST /api/auth", testAuth.Handler.CreateAuthToken)
+	testMux.HandleFunc("GET /admin", adminOnly)
+	mux = testMux
+}
+
+// issueToken asks for a token for body and returns the response with the
+// claim the token carries, if one was issued.
+func issueToken(t *testing.T, body string) (*http.Response, *ModelClaim) {
+	req := httptest.NewRequest(http.MethodPost, "/api/auth", strings.NewReader(body))
+	req.Header.Add("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+
+	mux.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		return recorder.Result(), nil
+	}
+
+	res, err := ParseToJSON[ModelRead](*recorder.Result())
+	require.NoError(t, err)
+
+	token, err := testAuth.service.getToken(res.Token)
+	require.NoError(t, err)
+
+	return recorder.Result(), token.Claims.(*ModelClaim)
 }
 
 func TestAuth(t *testing.T) {
-	payload := AuthCreateModel{Email: "shirohige65@rocketmail.com"}
+	payload := ModelCreate{Email: "shirohige65@rocketmail.com"}
 
-	testScenarios := []testScenarioWithInput[AuthCreateModel, string]{
+	testScenarios := []testScenarioWithInput[ModelCreate, string]{
 		NewTestScenarioWithInput(payload, http.StatusOK, ""),
 	}
 
@@ -105,7 +145,7 @@ func TestAuth(t *testing.T) {
 			return
 		}
 
-		actualResponseBody, err := ParseToJSON[AuthReadModel](*actual)
+		actualResponseBody, err := ParseToJSON[ModelRead](*actual)
 		if !assert.Nil(t, err, excpectedStr(nil, err)) {
 			return
 		}
@@ -113,3 +153,90 @@ func TestAuth(t *testing.T) {
 		assert.NotEqual(t, expected.data, actualResponseBody.Token, excpectedStr(expected.data, actualResponseBody.Token))
 	}
 }
+
+func TestAuthRole(t *testing.T) {
+	_, claim := issueToken(t, `{"email": "shirohige65@rocketmail.com"}`)
+	require.NotNil(t, claim)
+	assert.Equal(t, RoleUser, claim.Role)
+
+	_, claim = issueToken(t, `{"email": "admin@example.com"}`)
+	require.NotNil(t, claim)
+	assert.Equal(t, RoleUser, claim.Role, "an admin email alone must not be issued the admin role")
+
+	_, claim = issueToken(t, `{"email": " Admin@Example.com", "admin_key": "`+testAdminKey+`"}`)
+	require.NotNil(t, claim)
+	assert.Equal(t, RoleAdmin, claim.Role)
+
+	res, claim := issueToken(t, `{"email": "admin@example.com", "admin_key": "guess"}`)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Nil(t, claim)
+
+	res, claim = issueToken(t, `{"email": "shirohige65@rocketmail.com", "admin_key": "`+testAdminKey+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "the admin key only works for admin emails")
+	assert.Nil(t, claim)
+
+	res, claim = issueToken(t, `{"email": "shirohige65@rocketmail.com", "role": "admin"}`)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "clients must not pick their own role")
+	assert.Nil(t, claim)
+
+	withoutKey := New([]byte("0123456789abcdef0123456789abcdef"), nil)
+	withoutKey.SetAdminEmails([]string{testAdminEmail})
+	_, err := withoutKey.service.generateJWT(ModelCreate{Email: testAdminEmail, AdminKey: ""})
+	assert.NoError(t, err)
+	_, err = withoutKey.service.generateJWT(ModelCreate{Email: testAdminEmail, AdminKey: "anything"})
+	assert.ErrorIs(t, err, errInvalidAdminKey, "no one is admin without a configured key")
+
+	testAuth.SetAdminEmails(nil)
+	t.Cleanup(func() { testAuth.SetAdminEmails([]string{testAdminEmail}) })
+
+	res, claim = issueToken(t, `{"email": "admin@example.com", "admin_key": "`+testAdminKey+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "removed admins should not be issued admin tokens")
+	assert.Nil(t, claim)
+}
+
+func TestRequireRole(t *testing.T) {
+	token := func(payload ModelCreate) string {
+		signed, err := testAuth.service.generateJWT(payload)
+		require.NoError(t, err)
+
+		return signed
+	}
+
+	testScenarios := map[string]struct {
+		token      string
+		statusCode int
+	}{
+		"admin":         {token: token(ModelCreate{Email: testAdminEmail, AdminKey: testAdminKey}), statusCode: http.StatusNoContent},
+		"admin email":   {token: token(ModelCreate{Email: testAdminEmail}), statusCode: http.StatusForbidden},
+		"user":          {token: token(ModelCreate{Email: "shirohige65@rocketmail.com"}), statusCode: http.StatusForbidden},
+		"invalid token": {token: "not-a-token", statusCode: http.StatusBadRequest},
+	}
+
+	for name, testScenario := range testScenarios {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.Header.Set(RequestHeaderAuthKey, "Bearer "+testScenario.token)
+			recorder := httptest.NewRecorder()
+
+			mux.ServeHTTP(recorder, req)
+
+			assert.Equal(t, testScenario.statusCode, recorder.Code, excpectedStr(testScenario.statusCode, recorder.Code))
+		})
+	}
+
+	t.Run("without VerifyJWT", func(t *testing.T) {
+		handler := testAuth.Middleware.RequireRole(RoleAdmin)(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		recorder := httptest.NewRecorder()
+
+		handler(recorder, httptest.NewRequest(http.MethodGet, "/admin", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+}
+
+func TestCheckSigningKey(t *testing.T) {
+	assert.NoError(t, testAuth.CheckSigningKey(context.Background()))
+	assert.ErrorIs(t, New(nil, nil).CheckSigningKey(context.Background()), errSigningKeyMissing)
+}
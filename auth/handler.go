@@ -1,15 +1,18 @@
 package auth
 
 import (
-	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 
 	"github.com/mmiftahrzki/customer/logger"
+	"github.com/mmiftahrzki/customer/requests"
 	"github.com/mmiftahrzki/customer/responses"
 	"github.com/sirupsen/logrus"
 )
 
+const maxBodyBytes int64 = 1 << 10
+
 type handler struct {
 	service service
 	log     *logrus.Entry
@@ -25,23 +28,28 @@ func newHandler(svc service) handler {
 func (h *handler) CreateAuthToken(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
-	payload := ModelCreate{}
+	var decodeErr *requests.DecodeError
 
-	json_decoder := json.NewDecoder(r.Body)
-	err := json_decoder.Decode(&payload)
-	if err != nil {
-		log.Println(err)
+	payload := ModelCreate{}
 
-		w.WriteHeader(http.StatusBadRequest)
+	err := requests.DecodeJson(w, r, &payload, maxBodyBytes)
+	if errors.As(err, &decodeErr) {
+		responses.Error(w, r, decodeErr.Status, decodeErr.Message)
 
 		return
 	}
 
 	token, err := h.service.generateJWT(payload)
+	if errors.Is(err, errInvalidAdminKey) {
+		responses.Error(w, r, http.StatusUnauthorized, "invalid admin key")
+
+		return
+	}
+
 	if err != nil {
 		log.Println(err)
 
-		responses.Error(w, http.StatusInternalServerError, "errors occured when generating JWT")
+		responses.Error(w, r, http.StatusInternalServerError, "errors occured when generating JWT")
 
 		return
 	}
@@ -3,8 +3,11 @@ package auth
 import (
 	"context"
 	"net/http"
+	"slices"
 
+	"github.com/mmiftahrzki/customer/logger"
 	"github.com/mmiftahrzki/customer/responses"
+	"github.com/sirupsen/logrus"
 )
 
 type middleware struct {
@@ -22,50 +25,47 @@ func (m *middleware) VerifyJWT(next http.HandlerFunc) http.HandlerFunc {
 		authValue := r.Header.Get(RequestHeaderAuthKey)
 		tokenStr, err := extractAuthTokenStr(authValue)
 		if err != nil {
-			responses.Error(w, http.StatusBadRequest, err.Error())
+			responses.Error(w, r, http.StatusBadRequest, err.Error())
 
 			return
 		}
 
 		token, err := m.service.getToken(tokenStr)
 		if err != nil {
-			responses.Error(w, http.StatusBadRequest, err.Error())
+			responses.Error(w, r, http.StatusBadRequest, err.Error())
 
 			return
 		}
 
+		if claim, ok := token.Claims.(*ModelClaim); ok {
+			logger.AddFields(r.Context(), logrus.Fields{"user": claim.Email})
+		}
+
 		r = r.WithContext(context.WithValue(r.Context(), JWTContextKey, token.Claims))
 
 		next.ServeHTTP(w, r)
 	})
 }
 
-// func (m *middleware) timeoutMiddleware(next http.HandlerFunc) http.HandlerFunc {
-// 	return func(w http.ResponseWriter, r *http.Request) {
-// 		const timeoutDuration time.Duration = 5 * time.Second
-// 		var delayQueryStr string
-// 		var delayInMs int
-// 		var strConvErr error
-
-// 		delayQueryStr = r.URL.Query().Get("delay")
-
-// 		if delayQueryStr == "" {
-// 			next.ServeHTTP(w, r)
-// 		}
+// RequireRole only lets requests through whose verified JWT claim carries one
+// of roles. It must run after VerifyJWT.
+func (m *middleware) RequireRole(roles ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			claim, ok := r.Context().Value(JWTContextKey).(*ModelClaim)
+			if !ok {
+				responses.Error(w, r, http.StatusUnauthorized, errEmptyAuth.Error())
 
-// 		delayInMs, strConvErr = strconv.Atoi(delayQueryStr)
-// 		if strConvErr != nil {
-// 			responses.Error(w, http.StatusUnprocessableEntity, "invalid delay duration value")
+				return
+			}
 
-// 			return
-// 		}
+			if !slices.Contains(roles, claim.Role) {
+				responses.Error(w, r, http.StatusForbidden, errForbidden.Error())
 
-// 		delayDuration := time.Duration(delayInMs) * time.Millisecond
-// 		ctxWithTimeout, cancel := context.WithTimeout(r.Context(), timeoutDuration)
-// 		defer cancel()
+				return
+			}
 
-// 		r = r.WithContext(ctxWithTimeout)
-
-// 		next.ServeHTTP(w, r)
-// 	}
-// }
+			next.ServeHTTP(w, r)
+		}
+	}
+}
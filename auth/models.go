@@ -2,8 +2,14 @@ package auth
 
 import "github.com/golang-jwt/jwt/v4"
 
+const (
+	RoleAdmin string = "admin"
+	RoleUser  string = "user"
+)
+
 type ModelClaim struct {
 	Email string `json:"email"`
+	Role  string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -11,6 +17,9 @@ type ModelRead struct {
 	Token string `json:"token"`
 }
 
+// ModelCreate asks for a token for Email. AdminKey proves the caller may be
+// issued the admin role; without it every email gets the user role.
 type ModelCreate struct {
-	Email string `json:"email"`
+	Email    string `json:"email"`
+	AdminKey string `json:"admin_key,omitempty"`
 }
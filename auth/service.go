@@ -1,9 +1,11 @@
 package auth
 
 import (
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -14,6 +16,8 @@ import (
 type contextKey int
 type service struct {
 	signingKey []byte
+	adminKey   []byte
+	admins     *atomic.Pointer[map[string]bool]
 	log        *logrus.Entry
 }
 
@@ -22,12 +26,49 @@ const RequestHeaderAuthKey string = "Authorization"
 
 var errEmptyAuth = errors.New("auth: authorization header not found")
 var errInvalidAuth = errors.New("auth: authorization header invalid")
+var errForbidden = errors.New("auth: insufficient role")
+var errInvalidAdminKey = errors.New("auth: invalid admin key")
 
-func newService(signingKey []byte) service {
-	return service{
+func newService(signingKey []byte, adminKey []byte) service {
+	svc := service{
 		signingKey: signingKey[:],
+		adminKey:   adminKey,
+		admins:     &atomic.Pointer[map[string]bool]{},
 		log:        logger.GetLogger().WithField("component", "auth/service"),
 	}
+	svc.setAdminEmails(nil)
+
+	return svc
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (s *service) setAdminEmails(emails []string) {
+	admins := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		admins[normalizeEmail(email)] = true
+	}
+
+	s.admins.Store(&admins)
+}
+
+// roleOf is the role issued for payload. Without an admin key it is user,
+// whatever the email. With one, the key must match the configured admin key
+// and the email must be one of the admin emails to be issued admin; anything
+// else is errInvalidAdminKey. Clients never choose their own role.
+func (s *service) roleOf(payload ModelCreate) (string, error) {
+	if payload.AdminKey == "" {
+		return RoleUser, nil
+	}
+
+	validKey := len(s.adminKey) > 0 && subtle.ConstantTimeCompare([]byte(payload.AdminKey), s.adminKey) == 1
+	if !validKey || payload.Email == "" || !(*s.admins.Load())[normalizeEmail(payload.Email)] {
+		return "", errInvalidAdminKey
+	}
+
+	return RoleAdmin, nil
 }
 
 func extractAuthTokenStr(auth_value string) (string, error) {
@@ -48,9 +89,16 @@ func extractAuthTokenStr(auth_value string) (string, error) {
 }
 
 func (s *service) generateJWT(payload ModelCreate) (string, error) {
+	role, err := s.roleOf(payload)
+	if err != nil {
+		return "", err
+	}
+
 	registerdClaims := jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(30 * time.Minute))}
+
 	claim := ModelClaim{
 		Email:            payload.Email,
+		Role:             role,
 		RegisteredClaims: registerdClaims,
 	}
 
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/mmiftahrzki/customer/metrics"
+)
+
+var hits = metrics.GetRegistry().NewCounterVec("cache_hits_total",
+	"Lookups answered from a cache, by cache.", "cache")
+
+var misses = metrics.GetRegistry().NewCounterVec("cache_misses_total",
+	"Lookups a cache had to load, by cache.", "cache")
+
+// Cache stores values by key. Implementations decide how long they keep
+// them and must be safe for concurrent use.
+type Cache[V any] interface {
+	Get(key string) (V, bool)
+	Set(key string, value V)
+	Delete(key string)
+	Clear()
+}
+
+// ReadThrough answers lookups from a Cache, loading what it lacks. Loads of
+// the same key made at the same time share one call. A nil *ReadThrough
+// always loads, so caching can be turned off without the callers knowing.
+type ReadThrough[V any] struct {
+	name        string
+	store       Cache[V]
+	loadTimeout time.Duration
+	group       group[V]
+	// generation changes with every invalidation, so a load that started
+	// before one neither stores its result nor is joined by later lookups.
+	generation atomic.Uint64
+}
+
+// NewReadThrough returns a read-through cache over store. name labels its
+// metrics and loadTimeout bounds every load; zero leaves loads unbounded.
+func NewReadThrough[V any](name string, store Cache[V], loadTimeout time.Duration) *ReadThrough[V] {
+	return &ReadThrough[V]{name: name, store: store, loadTimeout: loadTimeout}
+}
+
+// Get returns the value cached for key or, on a miss, the one load returns,
+// caching it when keep reports it is worth keeping. Errors are not cached.
+//
+// A load is shared, so it does not run on the ctx of whichever lookup started
+// it: it keeps ctx's values but not its cancellation, and gets the load
+// timeout instead. Each lookup stops waiting when its own ctx is done.
+func (rt *ReadThrough[V]) Get(ctx context.Context, key string, load func(ctx context.Context) (V, error), keep func(V) bool) (V, error) {
+	if rt == nil {
+		return load(ctx)
+	}
+
+	if value, ok := rt.store.Get(key); ok {
+		hits.Inc(rt.name)
+
+		return value, nil
+	}
+
+	misses.Inc(rt.name)
+
+	generation := rt.generation.Load()
+
+	return rt.group.do(ctx, key+"@"+strconv.FormatUint(generation, 10), func() (V, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		if rt.loadTimeout > 0 {
+			var cancel context.CancelFunc
+			loadCtx, cancel = context.WithTimeout(loadCtx, rt.loadTimeout)
+			defer cancel()
+		}
+
+		value, err := load(loadCtx)
+		if err == nil && keep(value) && rt.generation.Load() == generation {
+			rt.store.Set(key, value)
+		}
+
+		return value, err
+	})
+}
+
+// Invalidate drops what is cached for key.
+func (rt *ReadThrough[V]) Invalidate(key string) {
+	if rt == nil {
+		return
+	}
+
+	rt.generation.Add(1)
+	rt.store.Delete(key)
+}
+
+// Clear drops everything cached.
+func (rt *ReadThrough[V]) Clear() {
+	if rt == nil {
+		return
+	}
+
+	rt.generation.Add(1)
+	rt.store.Clear()
+}
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Now()
+	c := NewLRU[int](2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	_, ok := c.Get("a")
+	require.True(t, ok)
+
+	c.Set("c", 3)
+	_, ok = c.Get("b")
+	assert.False(t, ok, "least recently used entry should be evicted")
+
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	now = now.Add(time.Minute)
+	_, ok = c.Get("a")
+	assert.False(t, ok, "expired entry should be dropped")
+
+	c.Set("d", 4)
+	c.Delete("d")
+	_, ok = c.Get("d")
+	assert.False(t, ok)
+
+	c.Set("e", 5)
+	c.Clear()
+	_, ok = c.Get("e")
+	assert.False(t, ok)
+}
+
+func TestReadThrough(t *testing.T) {
+	ctx := context.Background()
+	rt := NewReadThrough("test", NewLRU[string](10, time.Minute), time.Minute)
+	keep := func(value string) bool { return value != "" }
+
+	loads := 0
+	load := func(value string, err error) func(context.Context) (string, error) {
+		return func(context.Context) (string, error) {
+			loads++
+
+			return value, err
+		}
+	}
+
+	value, err := rt.Get(ctx, "a", load("first", nil), keep)
+	require.NoError(t, err)
+	assert.Equal(t, "first", value)
+
+	value, err = rt.Get(ctx, "a", load("second", nil), keep)
+	require.NoError(t, err)
+	assert.Equal(t, "first", value)
+	assert.Equal(t, 1, loads)
+
+	rt.Invalidate("a")
+	value, _ = rt.Get(ctx, "a", load("second", nil), keep)
+	assert.Equal(t, "second", value)
+
+	failure := errors.New("failure")
+	_, err = rt.Get(ctx, "b", load("", failure), keep)
+	assert.ErrorIs(t, err, failure)
+	_, err = rt.Get(ctx, "b", load("", nil), keep)
+	assert.NoError(t, err)
+	_, err = rt.Get(ctx, "b", load("loaded", nil), keep)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, loads, "errors and unkept values should not be cached")
+
+	var disabled *ReadThrough[string]
+	value, err = disabled.Get(ctx, "a", load("uncached", nil), keep)
+	require.NoError(t, err)
+	assert.Equal(t, "uncached", value)
+	disabled.Invalidate("a")
+	disabled.Clear()
+}
+
+func TestReadThroughSharesLoads(t *testing.T) {
+	rt := NewReadThrough("test", NewLRU[int](10, time.Minute), time.Minute)
+	release := make(chan struct{})
+	var loads atomic.Int32
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			value, err := rt.Get(context.Background(), "a", func(context.Context) (int, error) {
+				loads.Add(1)
+				<-release
+
+				return 42, nil
+			}, func(int) bool { return true })
+			assert.NoError(t, err)
+			assert.Equal(t, 42, value)
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+}
+
+func TestReadThroughInvalidateDuringLoad(t *testing.T) {
+	ctx := context.Background()
+	rt := NewReadThrough("test", NewLRU[string](10, time.Minute), time.Minute)
+	keep := func(string) bool { return true }
+
+	value, err := rt.Get(ctx, "a", func(context.Context) (string, error) {
+		rt.Invalidate("a")
+
+		return "stale", nil
+	}, keep)
+	require.NoError(t, err)
+	assert.Equal(t, "stale", value)
+
+	value, err = rt.Get(ctx, "a", func(context.Context) (string, error) {
+		return "fresh", nil
+	}, keep)
+	require.NoError(t, err)
+	assert.Equal(t, "fresh", value, "a load overtaken by an invalidation should not be cached")
+}
+
+func TestReadThroughWaitersCancel(t *testing.T) {
+	rt := NewReadThrough("test", NewLRU[string](10, time.Minute), time.Minute)
+	keep := func(string) bool { return true }
+	started, release := make(chan struct{}), make(chan struct{})
+	loadErr := make(chan error, 1)
+
+	load := func(ctx context.Context) (string, error) {
+		close(started)
+		<-release
+		loadErr <- ctx.Err()
+
+		return "loaded", nil
+	}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := rt.Get(first, "a", load, keep)
+		firstErr <- err
+	}()
+	<-started
+
+	second, cancelSecond := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelSecond()
+	_, err := rt.Get(second, "a", load, keep)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "a waiter should stop on its own deadline")
+
+	cancel()
+	assert.ErrorIs(t, <-firstErr, context.Canceled, "the caller that started the load should stop when cancelled")
+
+	third := make(chan string, 1)
+	go func() {
+		value, err := rt.Get(context.Background(), "a", load, keep)
+		assert.NoError(t, err)
+		third <- value
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	assert.Equal(t, "loaded", <-third, "the load should be shared with the remaining waiter")
+	assert.NoError(t, <-loadErr, "the load should outlive the callers that gave up")
+
+	value, err := rt.Get(context.Background(), "a", func(context.Context) (string, error) {
+		return "reloaded", nil
+	}, keep)
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", value, "the shared load should have been cached")
+}
+
+func TestReadThroughLoadTimeout(t *testing.T) {
+	rt := NewReadThrough("test", NewLRU[string](10, time.Minute), 10*time.Millisecond)
+
+	ctx := context.WithValue(context.Background(), t, "value")
+	_, err := rt.Get(ctx, "a", func(ctx context.Context) (string, error) {
+		assert.Equal(t, "value", ctx.Value(t), "the load should keep the caller's values")
+		<-ctx.Done()
+
+		return "", ctx.Err()
+	}, func(string) bool { return true })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestReadThroughLoadPanics(t *testing.T) {
+	rt := NewReadThrough("test", NewLRU[string](10, time.Minute), time.Minute)
+
+	_, err := rt.Get(context.Background(), "a", func(context.Context) (string, error) {
+		panic("boom")
+	}, func(string) bool { return true })
+	assert.ErrorContains(t, err, "boom")
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// call is a load in flight, waited on by every lookup of its key.
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// group de-duplicates concurrent loads of the same key.
+type group[V any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[V]
+}
+
+// do starts fn unless a call for key is already running, then waits for the
+// call and returns its result. fn runs on its own, so a caller whose ctx is
+// done stops waiting without stopping fn for the others.
+func (g *group[V]) do(ctx context.Context, key string, fn func() (V, error)) (V, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*call[V]{}
+	}
+
+	c, ok := g.calls[key]
+	if !ok {
+		c = &call[V]{done: make(chan struct{})}
+		g.calls[key] = c
+
+		go g.run(key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, c.err
+	case <-ctx.Done():
+		var zero V
+
+		return zero, ctx.Err()
+	}
+}
+
+// run calls fn for c and hands its result to the callers waiting on it. A
+// panic in fn has no request left to unwind, so it becomes c's error.
+func (g *group[V]) run(key string, c *call[V], fn func() (V, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("cache: load panicked: %v", r)
+		}
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+
+		close(c.done)
+	}()
+
+	c.value, c.err = fn()
+}
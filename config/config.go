@@ -1,28 +1,274 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
-type baseConfig struct {
-	App      AppConfig      `mapstructure:"app"`
-	Database DatabaseConfig `mapstructure:"database"`
+// EnvPrefix prefixes the environment variables overriding the config, e.g.
+// CUSTOMER_DATABASE_HOST for database.host.
+const EnvPrefix string = "CUSTOMER"
+
+// fileSuffix marks an environment variable naming a file to read the value
+// from, e.g. CUSTOMER_DATABASE_PASSWORD_FILE, for secrets mounted as files.
+const fileSuffix string = "_FILE"
+
+// ErrHelp is returned by LoadConfig when -h or --help was passed.
+var ErrHelp = pflag.ErrHelp
+
+// Config is the whole application configuration.
+type Config struct {
+	App        AppConfig        `mapstructure:"app"`
+	Auth       AuthConfig       `mapstructure:"auth"`
+	CORS       CORSConfig       `mapstructure:"cors"`
+	Customer   CustomerConfig   `mapstructure:"customer"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Encryption EncryptionConfig `mapstructure:"encryption"`
+	RateLimit  RateLimitConfig  `mapstructure:"ratelimit"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	Logging    LoggingConfig    `mapstructure:"logging"`
 }
 
-func LoadConfig() (baseConfig, error) {
-	var cfg baseConfig
+// defaults are the values used for settings absent from every source.
+// Settings without a default, such as database.user or
+// auth.jwt_secret_key, must be provided.
+func defaults() Config {
+	return Config{
+		App: AppConfig{
+			Port:               8080,
+			HealthCheckTimeout: 2 * time.Second,
+			DrainDelay:         5 * time.Second,
+			ShutdownTimeout:    15 * time.Second,
+			RequestTimeout:     10 * time.Second,
+			MaxRequestTimeout:  25 * time.Second,
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "X-Request-ID", "X-Request-Timeout"},
+			ExposedHeaders: []string{"X-Request-ID", "Retry-After", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"},
+			MaxAge:         10 * time.Minute,
+		},
+		Customer: CustomerConfig{
+			PageSize:         25,
+			CacheSize:        1000,
+			CacheTTL:         30 * time.Second,
+			CacheLoadTimeout: 10 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:               "mysql",
+			Host:                 "localhost",
+			MaxConnection:        10,
+			ConnMaxLifetime:      30 * time.Minute,
+			ConnMaxIdleTime:      5 * time.Minute,
+			TLSMode:              "false",
+			ConnectTimeout:       5 * time.Second,
+			ReadTimeout:          30 * time.Second,
+			WriteTimeout:         30 * time.Second,
+			Loc:                  "UTC",
+			ConnectAttempts:      5,
+			ConnectBackoff:       time.Second,
+			ReplicaMaxLag:        5 * time.Second,
+			ReplicaCheckInterval: 5 * time.Second,
+			TxRetryAttempts:      3,
+			TxRetryBackoff:       20 * time.Millisecond,
+			TxRetryMaxBackoff:    500 * time.Millisecond,
+			SlowQueryThreshold:   200 * time.Millisecond,
+		},
+		RateLimit: RateLimitConfig{
+			Requests: 120,
+			Period:   time.Minute,
+			Burst:    30,
+		},
+		Tracing: TracingConfig{
+			ServiceName: "customer",
+			SampleRatio: 1,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+		},
+	}
+}
 
-	viper.SetConfigName("config")
-	viper.SetConfigType("json")
-	viper.AddConfigPath(".")
+// setting is a leaf of Config, addressed by its viper key.
+type setting struct {
+	key   string
+	value reflect.Value
+}
 
-	if err := viper.ReadInConfig(); err != nil {
-		return cfg, err
+// settings lists the scalar and string slice leaves of v. Maps and slices of
+// structs can only be set from the config file and are only listed when
+// withLists is set.
+func settings(prefix string, v reflect.Value, withLists bool) []setting {
+	out := []setting{}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+
+		name := field.Tag.Get("mapstructure")
+		if name == "" {
+			name = field.Name
+		}
+
+		key := strings.ToLower(name)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			out = append(out, settings(key, v.Field(i), withLists)...)
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() != reflect.String && !withLists:
+			continue
+		case field.Type.Kind() == reflect.Map && !withLists:
+			continue
+		default:
+			out = append(out, setting{key: key, value: v.Field(i)})
+		}
+	}
+
+	return out
+}
+
+func envName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func addFlag(flags *pflag.FlagSet, s setting) {
+	usage := fmt.Sprintf("overrides %s, also settable with %s", s.key, envName(s.key))
+
+	switch value := s.value.Interface().(type) {
+	case string:
+		flags.String(s.key, value, usage)
+	case bool:
+		flags.Bool(s.key, value, usage)
+	case int:
+		flags.Int(s.key, value, usage)
+	case uint16:
+		flags.Uint16(s.key, value, usage)
+	case float64:
+		flags.Float64(s.key, value, usage)
+	case time.Duration:
+		flags.Duration(s.key, value, usage)
+	case []string:
+		flags.StringSlice(s.key, value, usage)
+	default:
+		panic(fmt.Sprintf("config: no flag type for %s (%T)", s.key, value))
+	}
+}
+
+// load builds the config from args and the environment, returning it with
+// the path of the config file read, if any.
+func load(args []string) (Config, string, error) {
+	var cfg Config
+
+	v := viper.New()
+	flags := pflag.NewFlagSet("customer", pflag.ContinueOnError)
+	flags.SortFlags = false
+
+	configPath := flags.String("config", os.Getenv(EnvPrefix+"_CONFIG"), "path of the JSON config file")
+
+	all := settings("", reflect.ValueOf(defaults()), false)
+	for _, s := range all {
+		v.SetDefault(s.key, s.value.Interface())
+		addFlag(flags, s)
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return cfg, "", err
+	}
+
+	if *configPath != "" {
+		v.SetConfigFile(*configPath)
+	} else {
+		v.SetConfigName("config")
+		v.SetConfigType("json")
+		v.AddConfigPath(".")
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if *configPath != "" || !errors.As(err, &notFound) {
+			return cfg, "", fmt.Errorf("failed to read config: %w", err)
+		}
+	}
+
+	for _, s := range all {
+		if err := v.BindEnv(s.key, envName(s.key)); err != nil {
+			return cfg, "", err
+		}
+	}
+
+	// JWT_SECRET_KEY predates the CUSTOMER_ prefix and is still honoured.
+	if err := v.BindEnv("auth.jwt_secret_key", envName("auth.jwt_secret_key"), "JWT_SECRET_KEY"); err != nil {
+		return cfg, "", err
+	}
+
+	errs := []error{}
+
+	for _, s := range all {
+		path, ok := os.LookupEnv(envName(s.key) + fileSuffix)
+		if !ok || flags.Changed(s.key) {
+			continue
+		}
+
+		if _, set := os.LookupEnv(envName(s.key)); set {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s%s: %w", envName(s.key), fileSuffix, err))
+
+			continue
+		}
+
+		v.Set(s.key, strings.TrimRight(string(content), "\r\n"))
 	}
 
-	if err := viper.Unmarshal(&cfg); err != nil {
+	for _, s := range all {
+		if err := v.BindPFlag(s.key, flags.Lookup(s.key)); err != nil {
+			return cfg, "", err
+		}
+	}
+
+	if err := v.UnmarshalExact(&cfg); err != nil {
+		errs = append(errs, err)
+
+		return cfg, "", errors.Join(errs...)
+	}
+
+	errs = append(errs, cfg.validate()...)
+
+	return cfg, v.ConfigFileUsed(), errors.Join(errs...)
+}
+
+// LoadConfig builds the config from, in increasing order of precedence, the
+// defaults, the config file, <NAME>_FILE secret files, environment variables
+// and command-line flags, then validates it and reports every invalid
+// setting at once.
+//
+// The config file is config.json in the working directory, which may be
+// absent, unless --config or CUSTOMER_CONFIG names one explicitly.
+func LoadConfig(args []string) (Config, error) {
+	cfg, file, err := load(args)
+	if err != nil {
 		return cfg, err
 	}
 
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.args = args
+	state.file = file
+	state.current = cfg
+
 	return cfg, nil
 }
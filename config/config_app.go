@@ -1,5 +1,21 @@
 package config
 
+import "time"
+
+// AppConfig tunes the HTTP server. API requests get a deadline of
+// RequestTimeout unless the client asks for another one, which is capped at
+// MaxRequestTimeout; both should stay below the server's 30s write timeout.
+// Zero RequestTimeout sets no deadline and zero MaxRequestTimeout ignores
+// what clients ask for.
+//
+// On shutdown readiness fails for DrainDelay, while requests are still
+// served, so load balancers stop routing here before connections close;
+// in-flight requests then get ShutdownTimeout to finish.
 type AppConfig struct {
-	Port uint16
+	Port               uint16
+	HealthCheckTimeout time.Duration
+	DrainDelay         time.Duration
+	ShutdownTimeout    time.Duration
+	RequestTimeout     time.Duration
+	MaxRequestTimeout  time.Duration
 }
@@ -0,0 +1,11 @@
+package config
+
+// AuthConfig holds the key signing the issued JWTs. It is also the pepper
+// mixed into user password hashes. AdminEmails lists who may be issued tokens
+// with the admin role, and only when the token request carries AdminKey;
+// everyone else gets the user role.
+type AuthConfig struct {
+	JWTSecretKey string `mapstructure:"jwt_secret_key"`
+	AdminKey     string `mapstructure:"admin_key"`
+	AdminEmails  []string
+}
@@ -0,0 +1,20 @@
+package config
+
+import "time"
+
+// CORSConfig lets browser scripts from other origins call the API. An
+// origin is exact, e.g. "https://app.example.com", matches every subdomain,
+// e.g. "https://*.example.com", or is "*" for any origin, which cannot be
+// combined with AllowCredentials. No AllowedOrigins turns CORS off.
+//
+// Preflight requests are allowed AllowedMethods and AllowedHeaders, "*"
+// allowing every header, and may be cached by the browser for MaxAge.
+// ExposedHeaders lists the response headers scripts may read.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
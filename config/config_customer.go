@@ -0,0 +1,20 @@
+package config
+
+import "time"
+
+const maxPageSize int = 1000
+
+// CustomerConfig tunes the customer API. PageSize is how many customers a
+// list page holds and can be changed without a restart.
+//
+// Up to CacheSize customers, and as many list pages, are cached for CacheTTL
+// at most; every write through the API drops what it affects. Zero
+// CacheSize turns the cache off. A load into the cache is shared by every
+// request waiting on it, so it runs for up to CacheLoadTimeout whatever
+// happens to the request that started it.
+type CustomerConfig struct {
+	PageSize         int
+	CacheSize        int
+	CacheTTL         time.Duration
+	CacheLoadTimeout time.Duration
+}
@@ -1,10 +1,71 @@
 package config
 
+import "time"
+
+// DatabaseConfig describes the database connection.
+//
+// Driver is "mysql" (the default), "postgres" or "sqlite". For SQLite, Name
+// is the database file and the network, TLS and replica settings are unused.
+// Port defaults to the driver's usual one.
+//
+// TLSMode is "false" (the default), "true", "skip-verify" or "preferred", as
+// understood by the MySQL driver and mapped to the matching sslmode for
+// PostgreSQL. TLSCAFile, and TLSCertFile with TLSKeyFile for client
+// certificates, switch to a custom TLS config verifying the server against
+// that CA unless TLSMode is "skip-verify".
+//
+// MaxIdleConnection defaults to half of MaxConnection. Loc is the time zone
+// DATETIME values are read in. Params are extra DSN parameters, such as
+// sql_mode, and can only be set in the config file.
+//
+// The first connection is attempted ConnectAttempts times, waiting
+// ConnectBackoff after the first failure and twice as long after each
+// following one.
+//
+// Replicas are "host" or "host:port" addresses of read replicas, reached with
+// the same credentials and settings as the primary; the port defaults to
+// Port. Every ReplicaCheckInterval each replica is pinged and its replication
+// lag read, and a replica lagging more than ReplicaMaxLag is left out of
+// rotation until it catches up.
+//
+// A transaction failing on a deadlock, lock wait timeout or serialization
+// failure is run again up to TxRetryAttempts times in all, waiting a random
+// time below TxRetryBackoff after the first failure, doubling the bound after
+// each following one up to TxRetryMaxBackoff, and never past the request's
+// deadline.
+//
+// Statements taking SlowQueryThreshold or longer are logged, with their
+// values left out; zero turns the log off.
 type DatabaseConfig struct {
-	User          string
-	Password      string
-	Host          string
-	Port          uint16
-	Name          string
-	MaxConnection int
+	Driver               string
+	User                 string
+	Password             string
+	Host                 string
+	Port                 uint16
+	Name                 string
+	MaxConnection        int
+	MaxIdleConnection    int
+	ConnMaxLifetime      time.Duration
+	ConnMaxIdleTime      time.Duration
+	TLSMode              string
+	TLSCAFile            string
+	TLSCertFile          string
+	TLSKeyFile           string
+	TLSServerName        string
+	ConnectTimeout       time.Duration
+	ReadTimeout          time.Duration
+	WriteTimeout         time.Duration
+	Charset              string
+	Collation            string
+	Loc                  string
+	Params               map[string]string
+	ConnectAttempts      int
+	ConnectBackoff       time.Duration
+	Replicas             []string
+	ReplicaMaxLag        time.Duration
+	ReplicaCheckInterval time.Duration
+	TxRetryAttempts      int
+	TxRetryBackoff       time.Duration
+	TxRetryMaxBackoff    time.Duration
+	SlowQueryThreshold   time.Duration
 }
@@ -0,0 +1,8 @@
+package config
+
+// EncryptionConfig turns on field-level encryption when KeyringFile is set.
+// Columns are named as "table.column", e.g. "customer.email".
+type EncryptionConfig struct {
+	KeyringFile string
+	Columns     []string
+}
@@ -0,0 +1,25 @@
+package config
+
+import "time"
+
+// LoggingConfig sets the level ("debug", "info", ...), the format ("json",
+// "logfmt" or "text") and the outputs logs are written to. With no outputs
+// configured logs go to stdout.
+type LoggingConfig struct {
+	Level   string
+	Format  string
+	Outputs []LogOutputConfig
+}
+
+// LogOutputConfig is one log destination. Path is "stdout", "stderr" or a
+// file. A file is rotated once it grows past MaxSizeMB or has been open for
+// RotateInterval, whichever comes first; zero disables either trigger.
+// Rotated files are removed once there are more than MaxBackups of them or
+// they are older than MaxAge; zero keeps them.
+type LogOutputConfig struct {
+	Path           string
+	MaxSizeMB      int
+	RotateInterval time.Duration
+	MaxBackups     int
+	MaxAge         time.Duration
+}
@@ -0,0 +1,27 @@
+package config
+
+import "time"
+
+// RateLimitConfig limits how often each client may call the API. A client is
+// the API key sent in APIKeyHeader, if set, else its IP address. JWTs do not
+// tell clients apart, as anyone can be issued one for any email.
+//
+// Each client is granted Requests tokens per Period, up to Burst at once;
+// Burst defaults to Requests. Routes gives single routes a policy and a
+// bucket of their own, keyed by the route pattern, e.g. "POST /api/auth/{$}",
+// and can only be set in the config file. Zero Requests turns limiting off,
+// for all routes or for one.
+type RateLimitConfig struct {
+	Requests     int
+	Period       time.Duration
+	Burst        int
+	APIKeyHeader string
+	Routes       map[string]RateLimitPolicy
+}
+
+// RateLimitPolicy is the rate limit of a single route.
+type RateLimitPolicy struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret string = "0123456789abcdef0123456789abcdef"
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeConfig(t, `{
+		"app": {"port": 9000},
+		"database": {"user": "file-user", "host": "file-host", "name": "customer", "password": "file-password"}
+	}`)
+
+	secret := filepath.Join(t.TempDir(), "jwt")
+	os.WriteFile(secret, []byte(testSecret+"\n"), 0o600)
+
+	t.Setenv("CUSTOMER_DATABASE_HOST", "env-host")
+	t.Setenv("CUSTOMER_DATABASE_PORT", "3307")
+	t.Setenv("CUSTOMER_DATABASE_USER", "env-user")
+	t.Setenv("CUSTOMER_AUTH_JWT_SECRET_KEY_FILE", secret)
+
+	cfg, err := LoadConfig([]string{"--config", path, "--database.user", "flag-user", "--app.shutdowntimeout", "5s"})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, uint16(9000), cfg.App.Port, "from the file")
+	assert.Equal(t, 2*time.Second, cfg.App.HealthCheckTimeout, "from the defaults")
+	assert.Equal(t, 5*time.Second, cfg.App.ShutdownTimeout, "from a flag")
+	assert.Equal(t, "env-host", cfg.Database.Host, "env beats the file")
+	assert.Equal(t, uint16(3307), cfg.Database.Port)
+	assert.Equal(t, "flag-user", cfg.Database.User, "a flag beats env")
+	assert.Equal(t, "file-password", cfg.Database.Password)
+	assert.Equal(t, testSecret, cfg.Auth.JWTSecretKey, "read from the _FILE secret")
+}
+
+func TestLoadConfigLegacyJWTSecretKey(t *testing.T) {
+	path := writeConfig(t, `{"database": {"user": "u", "name": "customer"}}`)
+	t.Setenv("JWT_SECRET_KEY", testSecret)
+
+	cfg, err := LoadConfig([]string{"--config", path})
+	assert.Nil(t, err)
+	assert.Equal(t, testSecret, cfg.Auth.JWTSecretKey)
+}
+
+func TestLoadConfigReportsEveryInvalidField(t *testing.T) {
+	path := writeConfig(t, `{
+		"database": {"name": ""},
+		"logging": {"level": "loud", "format": "xml"},
+		"tracing": {"exporter": "otlp-file"}
+	}`)
+
+	_, err := LoadConfig([]string{"--config", path, "--app.port", "0"})
+	if !assert.NotNil(t, err) {
+		return
+	}
+
+	for _, key := range []string{
+		"app.port",
+		"auth.jwt_secret_key",
+		"database.user",
+		"database.name",
+		"logging.level",
+		"logging.format",
+		"tracing.file",
+	} {
+		assert.True(t, strings.Contains(err.Error(), key+":"), key)
+	}
+}
+
+func TestLoadConfigDatabaseDriver(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", testSecret)
+
+	cfg, err := LoadConfig([]string{"--database.driver", "sqlite", "--database.name", "customer.db"})
+	if assert.Nil(t, err, "sqlite needs no user") {
+		assert.Equal(t, "sqlite", cfg.Database.Driver)
+	}
+
+	_, err = LoadConfig([]string{"--database.driver", "oracle", "--database.user", "u", "--database.name", "n"})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "database.driver:")
+	}
+
+	_, err = LoadConfig([]string{"--database.driver", "postgres", "--database.user", "u", "--database.name", "n", "--database.tlsmode", "preferred"})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "database.tlsmode:")
+	}
+}
+
+func TestLoadConfigCORS(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", testSecret)
+	t.Setenv("CUSTOMER_CORS_ALLOWEDORIGINS", "https://app.example.com,https://*.example.com")
+
+	cfg, err := LoadConfig([]string{"--database.user", "u", "--database.name", "n"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"https://app.example.com", "https://*.example.com"}, cfg.CORS.AllowedOrigins)
+	}
+
+	t.Setenv("CUSTOMER_CORS_ALLOWEDORIGINS", "*,https://example.com/app,https://*example.com")
+
+	_, err = LoadConfig([]string{"--database.user", "u", "--database.name", "n", "--cors.allowcredentials"})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `cannot be combined with cors.allowcredentials`)
+		assert.Contains(t, err.Error(), `"https://example.com/app" is not an origin`)
+		assert.Contains(t, err.Error(), `"https://*example.com" is not an origin`)
+	}
+}
+
+func TestLoadConfigAdminEmails(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", testSecret)
+	t.Setenv("CUSTOMER_AUTH_ADMINEMAILS", "admin@example.com,ops@example.com")
+
+	_, err := LoadConfig([]string{"--database.user", "u", "--database.name", "n"})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "auth.admin_key: must be set when auth.adminemails is set")
+	}
+
+	t.Setenv("CUSTOMER_AUTH_ADMIN_KEY", "short")
+
+	_, err = LoadConfig([]string{"--database.user", "u", "--database.name", "n"})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "auth.admin_key: must be at least 32 bytes long")
+	}
+
+	t.Setenv("CUSTOMER_AUTH_ADMIN_KEY", testSecret)
+
+	cfg, err := LoadConfig([]string{"--database.user", "u", "--database.name", "n"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"admin@example.com", "ops@example.com"}, cfg.Auth.AdminEmails)
+		assert.Equal(t, testSecret, cfg.Auth.AdminKey)
+	}
+
+	t.Setenv("CUSTOMER_AUTH_ADMINEMAILS", "admin@example.com,admin")
+
+	_, err = LoadConfig([]string{"--database.user", "u", "--database.name", "n"})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `auth.adminemails: "admin" is not an email address`)
+	}
+}
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, `{"database": {"hots": "typo"}}`)
+	t.Setenv("JWT_SECRET_KEY", testSecret)
+
+	_, err := LoadConfig([]string{"--config", path, "--database.user", "u", "--database.name", "n"})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "hots")
+	}
+}
+
+func TestLoadConfigMissingExplicitFile(t *testing.T) {
+	_, err := LoadConfig([]string{"--config", filepath.Join(t.TempDir(), "missing.json")})
+	assert.NotNil(t, err)
+}
+
+func TestReload(t *testing.T) {
+	path := writeConfig(t, `{"database": {"user": "u", "name": "customer"}, "customer": {"pagesize": 25}}`)
+	t.Setenv("JWT_SECRET_KEY", testSecret)
+
+	_, err := LoadConfig([]string{"--config", path})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	calls := 0
+	Subscribe(func(previous Config, next Config) {
+		calls++
+		assert.Equal(t, 25, previous.Customer.PageSize)
+		assert.Equal(t, 50, next.Customer.PageSize)
+	})
+
+	log := logrus.NewEntry(logrus.New())
+
+	os.WriteFile(path, []byte(`{"database": {"user": "u", "name": "customer"}, "customer": {"pagesize": -1}}`), 0o600)
+	reload(log)
+	assert.Equal(t, 25, Current().Customer.PageSize, "an invalid config is rejected")
+	assert.Equal(t, 0, calls)
+
+	os.WriteFile(path, []byte(`{"database": {"user": "u", "name": "other"}, "customer": {"pagesize": 50}}`), 0o600)
+	reload(log)
+	assert.Equal(t, 50, Current().Customer.PageSize)
+	assert.Equal(t, "customer", Current().Database.Name, "structural settings wait for a restart")
+	assert.Equal(t, 1, calls)
+}
+
+func TestWatch(t *testing.T) {
+	path := writeConfig(t, `{"database": {"user": "u", "name": "customer"}, "customer": {"pagesize": 25}}`)
+	t.Setenv("JWT_SECRET_KEY", testSecret)
+
+	_, err := LoadConfig([]string{"--config", path})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	state.mu.Lock()
+	state.subscribers = nil
+	state.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		Watch(ctx, logrus.NewEntry(logrus.New()))
+	}()
+
+	assert.Eventually(t, func() bool {
+		os.WriteFile(path, []byte(`{"database": {"user": "u", "name": "customer"}, "customer": {"pagesize": 50}}`), 0o600)
+
+		return Current().Customer.PageSize == 50
+	}, 5*time.Second, 20*time.Millisecond)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("Watch should return once its context is done")
+	}
+}
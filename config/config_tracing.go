@@ -0,0 +1,11 @@
+package config
+
+// TracingConfig selects where spans are exported: "" disables tracing,
+// "stdout" prints them and "otlp-file" appends them in OTLP/JSON to File.
+// SampleRatio is the share of new traces recorded; zero records every trace.
+type TracingConfig struct {
+	Exporter    string
+	File        string
+	ServiceName string
+	SampleRatio float64
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// reloadable lists the keys, or key prefixes, whose changes are applied to
+// a running process. Changes to any other key are logged and ignored until
+// the next restart.
+var reloadable = []string{
+	"logging.level",
+	"auth.adminemails",
+	"customer.pagesize",
+	"database.slowquerythreshold",
+	"ratelimit.requests",
+	"ratelimit.period",
+	"ratelimit.burst",
+	"ratelimit.routes",
+	"cors",
+}
+
+// state is the config LoadConfig returned, kept so it can be reloaded with
+// the same flags when the file changes.
+var state struct {
+	mu          sync.Mutex
+	args        []string
+	file        string
+	current     Config
+	subscribers []func(previous Config, next Config)
+}
+
+// Subscribe registers fn to be called with the previous and the new config
+// each time a reload applies changes. Calls are serialized; fn should only
+// pick up the settings it cares about.
+func Subscribe(fn func(previous Config, next Config)) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.subscribers = append(state.subscribers, fn)
+}
+
+// Current returns the config in effect.
+func Current() Config {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return state.current
+}
+
+func isReloadable(key string) bool {
+	for _, prefix := range reloadable {
+		if key == prefix || strings.HasPrefix(key, prefix+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// change is a setting whose value differs between two configs.
+type change struct {
+	key string
+	old any
+	new any
+}
+
+func diff(previous Config, next Config) []change {
+	changes := []change{}
+
+	oldSettings := settings("", reflect.ValueOf(previous), true)
+	newSettings := settings("", reflect.ValueOf(next), true)
+
+	for i, s := range oldSettings {
+		oldValue, newValue := s.value.Interface(), newSettings[i].value.Interface()
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, change{key: s.key, old: oldValue, new: newValue})
+		}
+	}
+
+	return changes
+}
+
+// apply returns cfg with the reloadable settings of next.
+func apply(cfg Config, next Config) Config {
+	target := settings("", reflect.ValueOf(&cfg).Elem(), true)
+	source := settings("", reflect.ValueOf(next), true)
+
+	for i, s := range target {
+		if isReloadable(s.key) {
+			s.value.Set(source[i].value)
+		}
+	}
+
+	return cfg
+}
+
+// reload re-reads the config with the flags it was first loaded with. An
+// invalid config is rejected as a whole and the running one is kept.
+func reload(log *logrus.Entry) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	next, _, err := load(state.args)
+	if err != nil {
+		log.Errorf("config reload rejected, keeping the running config: %v", err)
+
+		return
+	}
+
+	previous := state.current
+	applied := []string{}
+
+	for _, c := range diff(previous, next) {
+		if !isReloadable(c.key) {
+			log.Warnf("config %s changed but only takes effect after a restart", c.key)
+
+			continue
+		}
+
+		applied = append(applied, fmt.Sprintf("%s: %v -> %v", c.key, c.old, c.new))
+	}
+
+	if len(applied) == 0 {
+		return
+	}
+
+	state.current = apply(previous, next)
+
+	log.Infof("config reloaded: %s", strings.Join(applied, ", "))
+
+	for _, fn := range state.subscribers {
+		fn(previous, state.current)
+	}
+}
+
+// Watch reloads the config each time its file changes, until ctx is done.
+// It returns at once when the config came from the environment and flags
+// only. The file's directory is watched rather than the file, so editors
+// that save by renaming and mounts that swap a symlink are picked up too.
+func Watch(ctx context.Context, log *logrus.Entry) {
+	state.mu.Lock()
+	file := state.file
+	state.mu.Unlock()
+
+	if file == "" {
+		return
+	}
+
+	file = filepath.Clean(file)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Errorf("cannot watch %s, config changes need a restart: %v", file, err)
+
+		return
+	}
+	defer watcher.Close()
+
+	err = watcher.Add(filepath.Dir(file))
+	if err != nil {
+		log.Errorf("cannot watch %s, config changes need a restart: %v", file, err)
+
+		return
+	}
+
+	log.Infof("watching %s for changes", file)
+
+	target, _ := filepath.EvalSymlinks(file)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-watcher.Errors:
+			log.Warnf("watching %s: %v", file, err)
+		case event := <-watcher.Events:
+			current, _ := filepath.EvalSymlinks(file)
+
+			changed := filepath.Clean(event.Name) == file && event.Op&(fsnotify.Write|fsnotify.Create) != 0
+			swapped := current != "" && current != target
+			if !changed && !swapped {
+				continue
+			}
+
+			target = current
+
+			reload(log)
+		}
+	}
+}
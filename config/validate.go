@@ -0,0 +1,403 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const minJWTSecretKeyLength int = 32
+
+// fieldError reports an invalid setting by its key.
+type fieldError struct {
+	key     string
+	message string
+}
+
+func (e fieldError) Error() string {
+	return e.key + ": " + e.message
+}
+
+func invalid(key string, format string, args ...any) error {
+	return fieldError{key: key, message: fmt.Sprintf(format, args...)}
+}
+
+func (c Config) validate() []error {
+	errs := []error{}
+
+	errs = append(errs, c.App.validate()...)
+	errs = append(errs, c.Auth.validate()...)
+	errs = append(errs, c.CORS.validate()...)
+	errs = append(errs, c.Customer.validate()...)
+	errs = append(errs, c.Database.validate()...)
+	errs = append(errs, c.Encryption.validate()...)
+	errs = append(errs, c.RateLimit.validate()...)
+	errs = append(errs, c.Tracing.validate()...)
+	errs = append(errs, c.Logging.validate()...)
+
+	return errs
+}
+
+func (c AppConfig) validate() []error {
+	errs := []error{}
+
+	if c.Port == 0 {
+		errs = append(errs, invalid("app.port", "must be set"))
+	}
+
+	if c.HealthCheckTimeout < 0 {
+		errs = append(errs, invalid("app.healthchecktimeout", "must not be negative"))
+	}
+
+	if c.DrainDelay < 0 {
+		errs = append(errs, invalid("app.draindelay", "must not be negative"))
+	}
+
+	if c.ShutdownTimeout < 0 {
+		errs = append(errs, invalid("app.shutdowntimeout", "must not be negative"))
+	}
+
+	if c.RequestTimeout < 0 {
+		errs = append(errs, invalid("app.requesttimeout", "must not be negative"))
+	}
+
+	if c.MaxRequestTimeout < 0 {
+		errs = append(errs, invalid("app.maxrequesttimeout", "must not be negative"))
+	}
+
+	return errs
+}
+
+func (c AuthConfig) validate() []error {
+	errs := []error{}
+
+	if len(c.JWTSecretKey) < minJWTSecretKeyLength {
+		errs = append(errs, invalid("auth.jwt_secret_key", "must be at least %d bytes long", minJWTSecretKeyLength))
+	}
+
+	if c.AdminKey != "" && len(c.AdminKey) < minJWTSecretKeyLength {
+		errs = append(errs, invalid("auth.admin_key", "must be at least %d bytes long", minJWTSecretKeyLength))
+	}
+
+	if len(c.AdminEmails) > 0 && c.AdminKey == "" {
+		errs = append(errs, invalid("auth.admin_key", "must be set when auth.adminemails is set"))
+	}
+
+	for _, email := range c.AdminEmails {
+		local, domain, found := strings.Cut(strings.TrimSpace(email), "@")
+		if !found || local == "" || domain == "" {
+			errs = append(errs, invalid("auth.adminemails", "%q is not an email address", email))
+		}
+	}
+
+	return errs
+}
+
+func (c CORSConfig) validate() []error {
+	errs := []error{}
+
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				errs = append(errs, invalid("cors.allowedorigins", "\"*\" cannot be combined with cors.allowcredentials"))
+			}
+
+			continue
+		}
+
+		if !validOrigin(origin) {
+			errs = append(errs, invalid("cors.allowedorigins", "%q is not an origin such as https://app.example.com or https://*.example.com", origin))
+		}
+	}
+
+	for _, method := range c.AllowedMethods {
+		if method == "" || strings.ContainsFunc(method, func(r rune) bool { return r < 'A' || r > 'Z' }) {
+			errs = append(errs, invalid("cors.allowedmethods", "%q is not an upper-case method name", method))
+		}
+	}
+
+	if c.MaxAge < 0 {
+		errs = append(errs, invalid("cors.maxage", "must not be negative"))
+	}
+
+	return errs
+}
+
+// validOrigin accepts scheme://host[:port] over http or https, where the host
+// may start with "*." to match its subdomains.
+func validOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && !strings.Contains(strings.TrimPrefix(u.Host, "*."), "*")
+}
+
+func (c DatabaseConfig) validate() []error {
+	errs := []error{}
+
+	switch c.Driver {
+	case "mysql", "postgres":
+		if c.User == "" {
+			errs = append(errs, invalid("database.user", "must be set"))
+		}
+
+		if c.Host == "" {
+			errs = append(errs, invalid("database.host", "must be set"))
+		}
+	case "sqlite":
+		if len(c.Replicas) > 0 {
+			errs = append(errs, invalid("database.replicas", "are not supported with sqlite"))
+		}
+	default:
+		errs = append(errs, invalid("database.driver", "%q is not one of mysql, postgres, sqlite", c.Driver))
+	}
+
+	if c.Name == "" {
+		errs = append(errs, invalid("database.name", "must be set"))
+	}
+
+	if c.MaxConnection <= 0 {
+		errs = append(errs, invalid("database.maxconnection", "must be positive"))
+	}
+
+	if c.MaxIdleConnection < 0 || c.MaxIdleConnection > c.MaxConnection {
+		errs = append(errs, invalid("database.maxidleconnection", "must be between 0 and database.maxconnection"))
+	}
+
+	durations := map[string]time.Duration{
+		"database.connmaxlifetime":    c.ConnMaxLifetime,
+		"database.connmaxidletime":    c.ConnMaxIdleTime,
+		"database.connecttimeout":     c.ConnectTimeout,
+		"database.readtimeout":        c.ReadTimeout,
+		"database.writetimeout":       c.WriteTimeout,
+		"database.connectbackoff":     c.ConnectBackoff,
+		"database.replicamaxlag":      c.ReplicaMaxLag,
+		"database.txretrybackoff":     c.TxRetryBackoff,
+		"database.txretrymaxbackoff":  c.TxRetryMaxBackoff,
+		"database.slowquerythreshold": c.SlowQueryThreshold,
+	}
+	for _, key := range sortedKeys(durations) {
+		if durations[key] < 0 {
+			errs = append(errs, invalid(key, "must not be negative"))
+		}
+	}
+
+	switch c.TLSMode {
+	case "", "false", "true", "skip-verify", "preferred":
+	default:
+		errs = append(errs, invalid("database.tlsmode", "%q is not one of false, true, skip-verify, preferred", c.TLSMode))
+	}
+
+	if c.Driver == "postgres" && c.TLSMode == "preferred" {
+		errs = append(errs, invalid("database.tlsmode", "preferred is not supported with postgres"))
+	}
+
+	if c.Driver == "postgres" && c.TLSServerName != "" {
+		errs = append(errs, invalid("database.tlsservername", "is not supported with postgres, which checks database.host"))
+	}
+
+	if (c.TLSCAFile != "" || c.TLSCertFile != "") && c.TLSMode != "true" && c.TLSMode != "skip-verify" {
+		errs = append(errs, invalid("database.tlsmode", "must be true or skip-verify when TLS files are set"))
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, invalid("database.tlscertfile", "must be set together with database.tlskeyfile"))
+	}
+
+	files := map[string]string{
+		"database.tlscafile":   c.TLSCAFile,
+		"database.tlscertfile": c.TLSCertFile,
+		"database.tlskeyfile":  c.TLSKeyFile,
+	}
+	for _, key := range sortedKeys(files) {
+		if files[key] == "" {
+			continue
+		}
+
+		if _, err := os.Stat(files[key]); err != nil {
+			errs = append(errs, invalid(key, "%v", err))
+		}
+	}
+
+	if _, err := time.LoadLocation(c.Loc); err != nil {
+		errs = append(errs, invalid("database.loc", "%q is not a time zone", c.Loc))
+	}
+
+	if c.ConnectAttempts < 1 {
+		errs = append(errs, invalid("database.connectattempts", "must be at least 1"))
+	}
+
+	if c.TxRetryAttempts < 1 {
+		errs = append(errs, invalid("database.txretryattempts", "must be at least 1"))
+	}
+
+	if c.TxRetryMaxBackoff < c.TxRetryBackoff {
+		errs = append(errs, invalid("database.txretrymaxbackoff", "must not be less than database.txretrybackoff"))
+	}
+
+	for _, replica := range c.Replicas {
+		host, port, err := net.SplitHostPort(replica)
+		if err != nil {
+			host, port = replica, ""
+		}
+
+		if host == "" || strings.ContainsAny(host, ":/ ") {
+			errs = append(errs, invalid("database.replicas", "%q is not a host or host:port", replica))
+
+			continue
+		}
+
+		if _, err := strconv.ParseUint(port, 10, 16); port != "" && err != nil {
+			errs = append(errs, invalid("database.replicas", "%q has an invalid port", replica))
+		}
+	}
+
+	if len(c.Replicas) > 0 && c.ReplicaCheckInterval <= 0 {
+		errs = append(errs, invalid("database.replicacheckinterval", "must be positive when replicas are set"))
+	}
+
+	return errs
+}
+
+func (c RateLimitConfig) validate() []error {
+	errs := RateLimitPolicy{Requests: c.Requests, Period: c.Period, Burst: c.Burst}.validate("ratelimit")
+
+	for _, pattern := range sortedKeys(c.Routes) {
+		errs = append(errs, c.Routes[pattern].validate(fmt.Sprintf("ratelimit.routes[%q]", pattern))...)
+	}
+
+	return errs
+}
+
+func (p RateLimitPolicy) validate(prefix string) []error {
+	errs := []error{}
+
+	if p.Requests < 0 {
+		errs = append(errs, invalid(prefix+".requests", "must not be negative"))
+	}
+
+	if p.Requests > 0 && p.Period <= 0 {
+		errs = append(errs, invalid(prefix+".period", "must be positive when requests is set"))
+	}
+
+	if p.Burst < 0 {
+		errs = append(errs, invalid(prefix+".burst", "must not be negative"))
+	}
+
+	return errs
+}
+
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (c EncryptionConfig) validate() []error {
+	errs := []error{}
+
+	if len(c.Columns) > 0 && c.KeyringFile == "" {
+		errs = append(errs, invalid("encryption.keyringfile", "must be set when columns are encrypted"))
+	}
+
+	for _, column := range c.Columns {
+		table, name, found := strings.Cut(column, ".")
+		if !found || table == "" || name == "" {
+			errs = append(errs, invalid("encryption.columns", "%q is not named as table.column", column))
+		}
+	}
+
+	return errs
+}
+
+func (c TracingConfig) validate() []error {
+	errs := []error{}
+
+	switch c.Exporter {
+	case "", "stdout":
+	case "otlp-file":
+		if c.File == "" {
+			errs = append(errs, invalid("tracing.file", "must be set for the otlp-file exporter"))
+		}
+	default:
+		errs = append(errs, invalid("tracing.exporter", "%q is not one of stdout, otlp-file", c.Exporter))
+	}
+
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		errs = append(errs, invalid("tracing.sampleratio", "must be between 0 and 1"))
+	}
+
+	return errs
+}
+
+func (c LoggingConfig) validate() []error {
+	errs := []error{}
+
+	if _, err := logrus.ParseLevel(c.Level); c.Level != "" && err != nil {
+		errs = append(errs, invalid("logging.level", "%q is not a log level", c.Level))
+	}
+
+	switch strings.ToLower(c.Format) {
+	case "", "json", "logfmt", "text":
+	default:
+		errs = append(errs, invalid("logging.format", "%q is not one of json, logfmt, text", c.Format))
+	}
+
+	for i, output := range c.Outputs {
+		key := fmt.Sprintf("logging.outputs[%d]", i)
+
+		if output.MaxSizeMB < 0 {
+			errs = append(errs, invalid(key+".maxsizemb", "must not be negative"))
+		}
+
+		if output.RotateInterval < 0 {
+			errs = append(errs, invalid(key+".rotateinterval", "must not be negative"))
+		}
+
+		if output.MaxBackups < 0 {
+			errs = append(errs, invalid(key+".maxbackups", "must not be negative"))
+		}
+
+		if output.MaxAge < 0 {
+			errs = append(errs, invalid(key+".maxage", "must not be negative"))
+		}
+	}
+
+	return errs
+}
+
+func (c CustomerConfig) validate() []error {
+	var errs []error
+
+	if c.PageSize < 1 || c.PageSize > maxPageSize {
+		errs = append(errs, invalid("customer.pagesize", "must be between 1 and %d", maxPageSize))
+	}
+
+	if c.CacheSize < 0 {
+		errs = append(errs, invalid("customer.cachesize", "must not be negative"))
+	}
+
+	if c.CacheSize > 0 && c.CacheTTL <= 0 {
+		errs = append(errs, invalid("customer.cachettl", "must be positive when customer.cachesize is set"))
+	}
+
+	if c.CacheSize > 0 && c.CacheLoadTimeout <= 0 {
+		errs = append(errs, invalid("customer.cacheloadtimeout", "must be positive when customer.cachesize is set"))
+	}
+
+	return errs
+}
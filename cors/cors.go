@@ -0,0 +1,283 @@
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mmiftahrzki/customer/responses"
+)
+
+// Policy says which other origins may call the API and how. Origins are
+// either exact, e.g. "https://app.example.com", match any subdomain, e.g.
+// "https://*.example.com", or "*" for every origin. No origins turns CORS
+// off, leaving browsers to block cross-origin calls.
+type Policy struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// wildcard matches the subdomains of suffix, which starts with a dot, under
+// scheme.
+type wildcard struct {
+	scheme string
+	suffix string
+}
+
+// rules is a Policy prepared for matching. Headers are kept lower-cased.
+type rules struct {
+	enabled     bool
+	anyOrigin   bool
+	origins     map[string]bool
+	wildcards   []wildcard
+	methods     []string
+	anyHeader   bool
+	headers     map[string]bool
+	exposed     string
+	credentials bool
+	maxAge      string
+}
+
+// Sharing answers preflight requests and marks the responses to allowed
+// origins so browsers let scripts read them.
+type Sharing struct {
+	route func(r *http.Request) string
+	rules atomic.Pointer[rules]
+}
+
+// New returns a Sharing that uses route to look up the pattern the mux
+// matches a request with, or "" when none does. It lets no origin through
+// until SetPolicy is called.
+func New(route func(r *http.Request) string) *Sharing {
+	s := &Sharing{route: route}
+	s.rules.Store(&rules{})
+
+	return s
+}
+
+// SetPolicy replaces the policy. Origins that cannot be parsed are skipped;
+// config validation rejects them beforehand.
+func (s *Sharing) SetPolicy(policy Policy) {
+	current := &rules{
+		enabled:     len(policy.AllowedOrigins) > 0,
+		origins:     map[string]bool{},
+		headers:     map[string]bool{},
+		exposed:     strings.Join(policy.ExposedHeaders, ", "),
+		credentials: policy.AllowCredentials,
+	}
+
+	for _, origin := range policy.AllowedOrigins {
+		if origin == "*" {
+			current.anyOrigin = true
+
+			continue
+		}
+
+		scheme, host, ok := parseOrigin(origin)
+		if !ok {
+			continue
+		}
+
+		if suffix, found := strings.CutPrefix(host, "*"); found {
+			current.wildcards = append(current.wildcards, wildcard{scheme: scheme, suffix: suffix})
+		} else {
+			current.origins[scheme+"://"+host] = true
+		}
+	}
+
+	for _, method := range policy.AllowedMethods {
+		current.methods = append(current.methods, strings.ToUpper(method))
+	}
+
+	for _, header := range policy.AllowedHeaders {
+		if header == "*" {
+			current.anyHeader = true
+		}
+
+		current.headers[strings.ToLower(header)] = true
+	}
+
+	if policy.MaxAge > 0 {
+		current.maxAge = strconv.Itoa(int(policy.MaxAge.Seconds()))
+	}
+
+	s.rules.Store(current)
+}
+
+// parseOrigin splits an origin, or a wildcard one such as
+// "https://*.example.com", into its scheme and lower-cased host, port
+// included. It refuses anything with a path, query or credentials.
+func parseOrigin(origin string) (string, string, bool) {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", "", false
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", "", false
+	}
+
+	host := strings.ToLower(u.Host)
+	if strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+		return "", "", false
+	}
+
+	return u.Scheme, host, true
+}
+
+func (rs *rules) allowsOrigin(origin string) bool {
+	if rs.anyOrigin {
+		return true
+	}
+
+	scheme, host, ok := parseOrigin(origin)
+	if !ok || strings.HasPrefix(host, "*") {
+		return false
+	}
+
+	if rs.origins[scheme+"://"+host] {
+		return true
+	}
+
+	for _, w := range rs.wildcards {
+		if scheme == w.scheme && strings.HasSuffix(host, w.suffix) && len(host) > len(w.suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Middleware must wrap the mux: the mux refuses OPTIONS on routes
+// registered for other methods, so preflight requests are answered here.
+func (s *Sharing) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current := s.rules.Load()
+		if !current.enabled {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		header := w.Header()
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if preflight {
+			addVary(header, "Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers")
+			s.preflight(w, r, current)
+
+			return
+		}
+
+		addVary(header, "Origin")
+
+		if origin := r.Header.Get("Origin"); origin != "" && current.allowsOrigin(origin) {
+			header.Set("Access-Control-Allow-Origin", origin)
+
+			if current.credentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if current.exposed != "" {
+				header.Set("Access-Control-Expose-Headers", current.exposed)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// preflight allows the requested method when it is both configured and one
+// the route is registered for.
+func (s *Sharing) preflight(w http.ResponseWriter, r *http.Request, current *rules) {
+	origin := r.Header.Get("Origin")
+	if origin == "" || !current.allowsOrigin(origin) {
+		responses.Error(w, r, http.StatusForbidden, fmt.Sprintf("origin %q is not allowed", origin))
+
+		return
+	}
+
+	methods := []string{}
+	for _, method := range current.methods {
+		probe := *r
+		probe.Method = method
+
+		if s.route(&probe) != "" {
+			methods = append(methods, method)
+		}
+	}
+
+	if len(methods) == 0 {
+		responses.Error(w, r, http.StatusNotFound, "not found")
+
+		return
+	}
+
+	requested := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if !slices.Contains(methods, requested) {
+		responses.Error(w, r, http.StatusMethodNotAllowed, fmt.Sprintf("method %s is not allowed", requested))
+
+		return
+	}
+
+	requestedHeaders := []string{}
+	for name := range strings.SplitSeq(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		if !current.anyHeader && !current.headers[name] {
+			responses.Error(w, r, http.StatusForbidden, fmt.Sprintf("header %q is not allowed", name))
+
+			return
+		}
+
+		requestedHeaders = append(requestedHeaders, name)
+	}
+
+	header := w.Header()
+	header.Set("Access-Control-Allow-Origin", origin)
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+
+	if len(requestedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(requestedHeaders, ", "))
+	}
+
+	if current.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if current.maxAge != "" {
+		header.Set("Access-Control-Max-Age", current.maxAge)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addVary adds names to the Vary header unless they are listed already.
+func addVary(header http.Header, names ...string) {
+	for _, name := range names {
+		listed := false
+		for _, value := range header.Values("Vary") {
+			for field := range strings.SplitSeq(value, ",") {
+				if strings.EqualFold(strings.TrimSpace(field), name) {
+					listed = true
+				}
+			}
+		}
+
+		if !listed {
+			header.Add("Vary", name)
+		}
+	}
+}
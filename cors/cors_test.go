@@ -0,0 +1,115 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHandler(policy Policy) http.HandlerFunc {
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	mux.HandleFunc("GET /items/{id}", ok)
+	mux.HandleFunc("PUT /items/{id}", ok)
+
+	sharing := New(func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+
+		return pattern
+	})
+	sharing.SetPolicy(policy)
+
+	return sharing.Middleware(mux.ServeHTTP)
+}
+
+func preflight(handler http.HandlerFunc, target string, origin string, method string, headers string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodOptions, target, nil)
+	r.Header.Set("Origin", origin)
+	r.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		r.Header.Set("Access-Control-Request-Headers", headers)
+	}
+
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	return w
+}
+
+func TestPreflight(t *testing.T) {
+	handler := newTestHandler(Policy{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	w := preflight(handler, "/items/1", "https://app.example.com", "PUT", "authorization, content-type")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, PUT", w.Header().Get("Access-Control-Allow-Methods"), "only the methods the route has")
+	assert.Equal(t, "authorization, content-type", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, w.Header().Values("Vary"))
+
+	w = preflight(handler, "/items/1", "https://api.eu.example.org", "GET", "")
+	assert.Equal(t, http.StatusNoContent, w.Code, "subdomains should match the wildcard")
+
+	for name, test := range map[string]struct {
+		target, origin, method, headers string
+		status                          int
+	}{
+		"unknown origin":      {"/items/1", "https://evil.example", "GET", "", http.StatusForbidden},
+		"wildcard apex":       {"/items/1", "https://example.org", "GET", "", http.StatusForbidden},
+		"wildcard scheme":     {"/items/1", "http://api.example.org", "GET", "", http.StatusForbidden},
+		"unknown route":       {"/missing", "https://app.example.com", "GET", "", http.StatusNotFound},
+		"method of no route":  {"/items/1", "https://app.example.com", "DELETE", "", http.StatusMethodNotAllowed},
+		"unconfigured method": {"/items/1", "https://app.example.com", "PATCH", "", http.StatusMethodNotAllowed},
+		"unconfigured header": {"/items/1", "https://app.example.com", "GET", "x-secret", http.StatusForbidden},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := preflight(handler, test.target, test.origin, test.method, test.headers)
+			assert.Equal(t, test.status, w.Code)
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
+
+func TestActualRequest(t *testing.T) {
+	handler := newTestHandler(Policy{
+		AllowedOrigins: []string{"https://app.example.com"},
+		ExposedHeaders: []string{"X-Request-ID"},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+	handler(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Request-ID", w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+
+	r = httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	r.Header.Set("Origin", "https://evil.example")
+	w = httptest.NewRecorder()
+	handler(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, "the browser, not the server, blocks other origins")
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+}
+
+func TestDisabled(t *testing.T) {
+	handler := newTestHandler(Policy{AllowedMethods: []string{"GET"}})
+
+	w := preflight(handler, "/items/1", "https://app.example.com", "GET", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code, "preflights should reach the mux")
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Values("Vary"))
+}
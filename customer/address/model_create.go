@@ -2,6 +2,8 @@ package address
 
 import (
 	"errors"
+
+	"github.com/mmiftahrzki/customer/requests"
 )
 
 type ModelCreate struct {
@@ -21,33 +23,31 @@ var errAddressCityIdIsNil = errors.New("city is required")
 var errAddressPostalCodeMoreThan10Chars = errors.New("postal code cannot be more than 10 characters")
 
 func (m ModelCreate) Validate() error {
-	if m.Address == nil {
-		return errAddressAddressIsNil
-	}
+	errs := requests.ValidationError{}
 
-	if len(*m.Address) > 50 {
-		return errAddressAddressMoreThan50Chars
+	if m.Address == nil {
+		errs = append(errs, requests.Invalid("address", errAddressAddressIsNil))
+	} else if len(*m.Address) > 50 {
+		errs = append(errs, requests.Invalid("address", errAddressAddressMoreThan50Chars))
 	}
 
 	if m.Address2 != nil && len(*m.Address2) > 50 {
-		return errAddressAddress2MoreThan50Chars
+		errs = append(errs, requests.Invalid("address2", errAddressAddress2MoreThan50Chars))
 	}
 
 	if m.District == nil {
-		return errAddressDistrictIsNil
-	}
-
-	if len(*m.District) > 20 {
-		return errAddressDistrictMoreThan20Chars
+		errs = append(errs, requests.Invalid("district", errAddressDistrictIsNil))
+	} else if len(*m.District) > 20 {
+		errs = append(errs, requests.Invalid("district", errAddressDistrictMoreThan20Chars))
 	}
 
 	if m.CityId == nil {
-		return errAddressCityIdIsNil
+		errs = append(errs, requests.Invalid("city_id", errAddressCityIdIsNil))
 	}
 
 	if m.PostalCode != nil && len(*m.PostalCode) > 10 {
-		return errAddressPostalCodeMoreThan10Chars
+		errs = append(errs, requests.Invalid("postal_code", errAddressPostalCodeMoreThan10Chars))
 	}
 
-	return nil
+	return errs.Err()
 }
@@ -1,5 +1,7 @@
 package address
 
+import "github.com/mmiftahrzki/customer/requests"
+
 type ModelUpdate struct {
 	Address    *string `json:"address"`
 	Address2   *string `json:"address2"`
@@ -8,21 +10,23 @@ type ModelUpdate struct {
 }
 
 func (m ModelUpdate) Validate() error {
+	errs := requests.ValidationError{}
+
 	if m.Address != nil && len(*m.Address) > 50 {
-		return errAddressAddressMoreThan50Chars
+		errs = append(errs, requests.Invalid("address", errAddressAddressMoreThan50Chars))
 	}
 
 	if m.Address2 != nil && len(*m.Address2) > 50 {
-		return errAddressAddress2MoreThan50Chars
+		errs = append(errs, requests.Invalid("address2", errAddressAddress2MoreThan50Chars))
 	}
 
 	if m.District != nil && len(*m.District) > 20 {
-		return errAddressDistrictMoreThan20Chars
+		errs = append(errs, requests.Invalid("district", errAddressDistrictMoreThan20Chars))
 	}
 
 	if m.PostalCode != nil && len(*m.PostalCode) > 10 {
-		return errAddressPostalCodeMoreThan10Chars
+		errs = append(errs, requests.Invalid("postal_code", errAddressPostalCodeMoreThan10Chars))
 	}
 
-	return nil
+	return errs.Err()
 }
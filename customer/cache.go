@@ -0,0 +1,80 @@
+package customer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/mmiftahrzki/customer/cache"
+	"github.com/mmiftahrzki/customer/config"
+	"github.com/mmiftahrzki/customer/database"
+)
+
+// serviceCache holds the customers and list pages the service reads
+// through. The zero value caches nothing.
+type serviceCache struct {
+	customers *cache.ReadThrough[modelSQL]
+	pages     *cache.ReadThrough[[]modelRead]
+}
+
+func newServiceCache(cfg config.CustomerConfig) serviceCache {
+	if cfg.CacheSize <= 0 {
+		return serviceCache{}
+	}
+
+	return serviceCache{
+		customers: cache.NewReadThrough("customer", cache.NewLRU[modelSQL](cfg.CacheSize, cfg.CacheTTL), cfg.CacheLoadTimeout),
+		pages:     cache.NewReadThrough("customer_page", cache.NewLRU[[]modelRead](cfg.CacheSize, cfg.CacheTTL), cfg.CacheLoadTimeout),
+	}
+}
+
+func customerKey(id int) string {
+	return strconv.Itoa(id)
+}
+
+func pageKey(direction string, id int, limit int) string {
+	return fmt.Sprintf("%s/%d/%d", direction, id, limit)
+}
+
+// invalidate drops the customers ids from the cache along with every list
+// page, as any write may change what the pages hold.
+func (c serviceCache) invalidate(ids ...int) {
+	for _, id := range ids {
+		c.customers.Invalidate(customerKey(id))
+	}
+
+	c.pages.Clear()
+}
+
+// clear drops everything, for writes that may change any customer, such as
+// one to an address several customers share.
+func (c serviceCache) clear() {
+	c.customers.Clear()
+	c.pages.Clear()
+}
+
+// readThrough looks key up in rt, except within a unit of work, which has to
+// see its own writes and so always loads.
+//
+// What a miss loads is kept for the whole TTL and handed to every lookup
+// joining the load, some of which may have to see their own writes, so it is
+// read from the primary: a lagging replica could otherwise bring back what
+// the last write invalidated.
+func readThrough[V any](ctx context.Context, rt *cache.ReadThrough[V], key string, load func(ctx context.Context) (V, error), keep func(V) bool) (V, error) {
+	if _, ok := database.TxFromContext(ctx); ok || rt == nil {
+		return load(ctx)
+	}
+
+	return rt.Get(ctx, key, func(ctx context.Context) (V, error) {
+		return load(database.WithPrimary(ctx))
+	}, keep)
+}
+
+// cachedPage reads a list page through the cache, handing out a copy so
+// callers cannot change what other requests are served.
+func (svc *service) cachedPage(ctx context.Context, key string, load func(ctx context.Context) ([]modelRead, error)) ([]modelRead, error) {
+	customers, err := readThrough(ctx, svc.cache.pages, key, load, func([]modelRead) bool { return true })
+
+	return slices.Clone(customers), err
+}
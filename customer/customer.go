@@ -1,13 +1,21 @@
 package customer
 
 import (
-	"database/sql"
+	"github.com/mmiftahrzki/customer/config"
+	"github.com/mmiftahrzki/customer/database"
+	"github.com/mmiftahrzki/customer/encryption"
 )
 
 type customer struct {
 	Handler handler
 }
 
-func New(db *sql.DB) customer {
-	return customer{newHandler(newService(newRepo(db)))}
+func New(db *database.DB, fields *encryption.Fields, cfg config.CustomerConfig) customer {
+	return customer{newHandler(newService(newRepo(db, fields), newServiceCache(cfg)))}
+}
+
+// SetPageSize changes how many customers a list page holds. Requests
+// already being served keep the size they started with.
+func (c customer) SetPageSize(size int) {
+	c.Handler.pageSize.Store(int64(size))
 }
@@ -1,8 +1,13 @@
+//go:build sakila
+
+// These tests run the handlers against a MySQL database loaded with the
+// Sakila sample data: go test -tags sakila ./customer
+
 package customer
 
 import (
 	"bytes"
-	"database/sql"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -18,7 +23,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-var db *sql.DB
+var db *database.DB
 var mux http.Handler
 var baseURL string
 
@@ -84,6 +89,7 @@ func init() {
 	var err error = nil
 	logger := logger.GetLogger()
 	cfg_db := config.DatabaseConfig{
+		Driver:        database.DriverMySQL,
 		Host:          "localhost",
 		Port:          3306,
 		User:          "root",
@@ -92,12 +98,23 @@ func init() {
 		MaxConnection: 10,
 	}
 
-	db, err = database.New(cfg_db)
+	db, err = database.New(context.Background(), cfg_db)
 	if err != nil {
 		logger.Fatalf("Database Error: %v\n", err)
 	}
 
-	mux = New(db).Handler
+	// only the handlers are under test here; the app's routes and middleware
+	// are not, so they are mounted directly.
+	handler := New(db, nil, config.CustomerConfig{}).Handler
+	customerMux := http.NewServeMux()
+	customerMux.HandleFunc("GET /api/customer/{$}", handler.GetMultiple)
+	customerMux.HandleFunc("GET /api/customer/{id}", handler.GetSingleById)
+	customerMux.HandleFunc("GET /api/customer/{id}/prev", handler.GetMultiplePrev)
+	customerMux.HandleFunc("GET /api/customer/{id}/next", handler.GetMultipleNext)
+	customerMux.HandleFunc("POST /api/customer/{$}", handler.PostSingle)
+	customerMux.HandleFunc("PUT /api/customer/{id}", handler.PutSingleById)
+	customerMux.HandleFunc("DELETE /api/customer/{id}", handler.DeleteSingleById)
+	mux = customerMux
 	baseURL = "http://localhost:1312/api/customer"
 }
 
@@ -128,7 +145,7 @@ func TestCustomerHandler(t *testing.T) {
 				return
 			}
 
-			actualResponseBody, err := ParseToJSON[responses.GetSingleResponse[readModel]](*actual)
+			actualResponseBody, err := ParseToJSON[responses.GetSingleResponse[modelRead]](*actual)
 			if !assert.Nil(t, err, excpectedStr(nil, err)) {
 				return
 			}
@@ -167,7 +184,7 @@ func TestCustomerHandler(t *testing.T) {
 				return
 			}
 
-			actualResponseBody, err := ParseToJSON[responses.GetMultipleResponse[readModel]](*actual)
+			actualResponseBody, err := ParseToJSON[responses.GetMultipleResponse[modelRead]](*actual)
 			if !assert.Nil(t, err, excpectedStr(nil, err)) {
 				return
 			}
@@ -230,7 +247,7 @@ func TestCustomerHandler(t *testing.T) {
 				return
 			}
 
-			actualResponseBody, err := ParseToJSON[responses.GetMultipleResponse[readModel]](*actual)
+			actualResponseBody, err := ParseToJSON[responses.GetMultipleResponse[modelRead]](*actual)
 			if !assert.Nil(t, err, excpectedStr(nil, err)) {
 				return
 			}
@@ -295,7 +312,7 @@ func TestCustomerHandler(t *testing.T) {
 				return
 			}
 
-			actualResponseBody, err := ParseToJSON[responses.GetMultipleResponse[readModel]](*actual)
+			actualResponseBody, err := ParseToJSON[responses.GetMultipleResponse[modelRead]](*actual)
 			if !assert.Nil(t, err, excpectedStr(nil, err)) {
 				return
 			}
@@ -313,7 +330,7 @@ func TestCustomerHandler(t *testing.T) {
 func TestCustomerProtectedHandler(t *testing.T) {
 	// go test ./customer/ -v -run "TestCustomer/create new single customer"
 	t.Run("create new single customer", func(t *testing.T) {
-		new_customer := createModel{
+		new_customer := modelCreate{
 			FirstName: "Muhammad Miftah",
 			LastName:  "Rizki",
 			Email:     "muhammadmiftahrizki@gmail.com",
@@ -324,6 +341,7 @@ func TestCustomerProtectedHandler(t *testing.T) {
 		if assert.Nil(t, err, excpectedStr(nil, err)) {
 			req := httptest.NewRequest(http.MethodPost, "/api/customer/", payload)
 			req.Header.Add("Content-Length", strconv.Itoa(payload.Len()))
+			req.Header.Add("Content-Type", "application/json")
 			recoder := httptest.NewRecorder()
 
 			mux.ServeHTTP(recoder, req)
@@ -340,7 +358,8 @@ func TestCustomerProtectedHandler(t *testing.T) {
 		if assert.Nil(t, err, excpectedStr(err, nil)) {
 			req := httptest.NewRequest(http.MethodPost, "/api/customer/", payload2)
 			req.Header.Add("Content-Length", strconv.Itoa(len_payload2))
-			res := responses.GetSingleResponse[readModel]{}
+			req.Header.Add("Content-Type", "application/json")
+			res := responses.GetSingleResponse[modelRead]{}
 			recoder := httptest.NewRecorder()
 
 			mux.ServeHTTP(recoder, req)
@@ -364,7 +383,7 @@ func TestCustomerProtectedHandler(t *testing.T) {
 		first_name := "KAREN EDITED"
 		last_name := "JACKSON EDITED"
 		email := "KAREN.JACKSON.EDITED@sakilacustomer.org"
-		customer := customerUpdateModel{
+		customer := modelUpdate{
 			FirstName: &first_name,
 			LastName:  &last_name,
 			Email:     &email,
@@ -375,7 +394,8 @@ func TestCustomerProtectedHandler(t *testing.T) {
 		if assert.Nil(t, err, excpectedStr(nil, err)) {
 			req := httptest.NewRequest(http.MethodPut, "/api/customer/13", payload)
 			req.Header.Add("Content-Length", strconv.Itoa(payload.Len()))
-			res := responses.GetSingleResponse[readModel]{}
+			req.Header.Add("Content-Type", "application/json")
+			res := responses.GetSingleResponse[modelRead]{}
 			recorder := httptest.NewRecorder()
 
 			mux.ServeHTTP(recorder, req)
@@ -394,7 +414,7 @@ func TestCustomerProtectedHandler(t *testing.T) {
 		first_name = "KAREN"
 		last_name = "JACKSON"
 		email = "KAREN.JACKSON@sakilacustomer.org"
-		customer = customerUpdateModel{
+		customer = modelUpdate{
 			FirstName: &first_name,
 			LastName:  &last_name,
 			Email:     &email,
@@ -405,7 +425,8 @@ func TestCustomerProtectedHandler(t *testing.T) {
 		if assert.Nil(t, err, excpectedStr(nil, err)) {
 			req := httptest.NewRequest(http.MethodPut, "/api/customer/13", payload)
 			req.Header.Add("Content-Length", strconv.Itoa(payload.Len()))
-			res := responses.GetSingleResponse[readModel]{}
+			req.Header.Add("Content-Type", "application/json")
+			res := responses.GetSingleResponse[modelRead]{}
 			recorder := httptest.NewRecorder()
 
 			mux.ServeHTTP(recorder, req)
@@ -446,7 +467,7 @@ func TestCustomerProtectedHandler(t *testing.T) {
 				return
 			}
 
-			actualResponseBody, err := ParseToJSON[responses.GetSingleResponse[readModel]](*actual)
+			actualResponseBody, err := ParseToJSON[responses.GetSingleResponse[modelRead]](*actual)
 			if !assert.Nil(t, err, excpectedStr(nil, err)) {
 				return
 			}
@@ -0,0 +1,165 @@
+package duplicate
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Threshold is the minimum score for a customer to be reported as a
+// duplicate candidate.
+const Threshold float64 = 0.5
+
+// A matching mailbox or a name at least minNameScore similar is enough on its
+// own to reach Threshold; a shared address only adds to the score.
+const (
+	weightEmail   float64 = 0.6
+	weightName    float64 = 0.6
+	weightAddress float64 = 0.2
+	minNameScore  float64 = 0.85
+)
+
+const (
+	ReasonEmail   string = "email"
+	ReasonName    string = "name"
+	ReasonAddress string = "address"
+)
+
+type Candidate struct {
+	Email     string
+	FirstName string
+	LastName  string
+	AddressId int
+}
+
+// NormalizeEmail lowercases and trims an email address. Gmail addresses also
+// lose the dots and "+tag" suffix of their local part, which Gmail ignores.
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	local, domain, found := strings.Cut(email, "@")
+	if !found {
+		return email
+	}
+
+	if domain == "googlemail.com" {
+		domain = "gmail.com"
+	}
+
+	if domain == "gmail.com" {
+		local, _, _ = strings.Cut(local, "+")
+		local = strings.ReplaceAll(local, ".", "")
+	}
+
+	return local + "@" + domain
+}
+
+// NormalizeName lowercases a name and collapses every run of whitespace or
+// punctuation into a single space.
+func NormalizeName(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+
+	return strings.Join(fields, " ")
+}
+
+// Similarity returns the Jaro-Winkler similarity of a and b, from 0 for
+// nothing in common to 1 for identical strings.
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(len(ra), len(rb))/2 - 1
+	window = max(window, 0)
+
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+
+	for i := range ra {
+		lo := max(0, i-window)
+		hi := min(len(rb), i+window+1)
+
+		for j := lo; j < hi; j++ {
+			if matchedB[j] || ra[i] != rb[j] {
+				continue
+			}
+
+			matchedA[i] = true
+			matchedB[j] = true
+			matches++
+
+			break
+		}
+	}
+
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+
+		for !matchedB[j] {
+			j++
+		}
+
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// Score rates how likely a and b describe the same person and lists the
+// signals that contributed to the score.
+func Score(a, b Candidate) (float64, []string) {
+	var score float64
+	reasons := []string{}
+
+	emailA, emailB := NormalizeEmail(a.Email), NormalizeEmail(b.Email)
+	if emailA != "" && emailA == emailB {
+		score += weightEmail
+		reasons = append(reasons, ReasonEmail)
+	}
+
+	nameA := NormalizeName(a.FirstName + " " + a.LastName)
+	nameB := NormalizeName(b.FirstName + " " + b.LastName)
+	if nameA != "" && nameB != "" {
+		similarity := Similarity(nameA, nameB)
+		if similarity >= minNameScore {
+			score += weightName * similarity
+			reasons = append(reasons, ReasonName)
+		}
+	}
+
+	if a.AddressId != 0 && a.AddressId == b.AddressId {
+		score += weightAddress
+		reasons = append(reasons, ReasonAddress)
+	}
+
+	return min(score, 1), reasons
+}
@@ -0,0 +1,73 @@
+package duplicate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	testScenarios := map[string]string{
+		"  Mary.Smith@Example.org ":    "mary.smith@example.org",
+		"mary.smith+promo@gmail.com":   "marysmith@gmail.com",
+		"Mary.Smith@googlemail.com":    "marysmith@gmail.com",
+		"mary.smith+promo@example.org": "mary.smith+promo@example.org",
+		"not-an-email":                 "not-an-email",
+	}
+
+	for input, expected := range testScenarios {
+		assert.Equal(t, expected, NormalizeEmail(input), input)
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	assert.Equal(t, "mary ann smith", NormalizeName("  Mary-Ann\tSMITH "))
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, Similarity("mary smith", "mary smith"))
+	assert.Equal(t, 0.0, Similarity("abc", "xyz"))
+	assert.InDelta(t, 0.961, Similarity("martha", "marhta"), 0.001)
+}
+
+func TestScore(t *testing.T) {
+	t.Run("same gmail mailbox", func(t *testing.T) {
+		a := Candidate{Email: "mary.smith@gmail.com", FirstName: "Mary", LastName: "Smith", AddressId: 5}
+		b := Candidate{Email: "MarySmith+shop@gmail.com", FirstName: "Jane", LastName: "Doe", AddressId: 9}
+
+		score, reasons := Score(a, b)
+
+		assert.GreaterOrEqual(t, score, Threshold)
+		assert.Equal(t, []string{ReasonEmail}, reasons)
+	})
+
+	t.Run("similar name at the same address", func(t *testing.T) {
+		a := Candidate{Email: "mary@example.org", FirstName: "Mary", LastName: "Smith", AddressId: 5}
+		b := Candidate{Email: "msmith@example.org", FirstName: "Mary", LastName: "Smyth", AddressId: 5}
+
+		score, reasons := Score(a, b)
+
+		assert.GreaterOrEqual(t, score, Threshold)
+		assert.Equal(t, []string{ReasonName, ReasonAddress}, reasons)
+	})
+
+	t.Run("similar name elsewhere", func(t *testing.T) {
+		a := Candidate{Email: "mary@example.org", FirstName: "Mary", LastName: "Smith", AddressId: 5}
+		b := Candidate{Email: "msmith@example.org", FirstName: "Mary", LastName: "Smyth", AddressId: 6}
+
+		score, reasons := Score(a, b)
+
+		assert.GreaterOrEqual(t, score, Threshold)
+		assert.Equal(t, []string{ReasonName}, reasons)
+	})
+
+	t.Run("different name at the same address", func(t *testing.T) {
+		a := Candidate{Email: "mary@example.org", FirstName: "Mary", LastName: "Smith", AddressId: 5}
+		b := Candidate{Email: "linda@example.org", FirstName: "Linda", LastName: "Williams", AddressId: 5}
+
+		score, reasons := Score(a, b)
+
+		assert.Less(t, score, Threshold)
+		assert.Equal(t, []string{ReasonAddress}, reasons)
+	})
+}
@@ -0,0 +1,325 @@
+package customer
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errExportUnknownFormat = errors.New("export format must be one of csv, ndjson or xlsx")
+var errExportUnknownColumn = errors.New("unknown export column")
+
+type exportColumn struct {
+	name  string
+	value func(modelExport) any
+}
+
+var exportColumns = []exportColumn{
+	{"id", func(m modelExport) any { return m.Id }},
+	{"email", func(m modelExport) any { return m.Email }},
+	{"first_name", func(m modelExport) any { return m.FirstName }},
+	{"last_name", func(m modelExport) any { return m.LastName }},
+	{"address_id", func(m modelExport) any { return m.AddressId }},
+	{"address", func(m modelExport) any { return m.Address }},
+	{"district", func(m modelExport) any { return m.District }},
+	{"city_id", func(m modelExport) any { return m.CityId }},
+	{"postal_code", func(m modelExport) any { return m.PostalCode }},
+	{"created_at", func(m modelExport) any { return m.CreatedAt }},
+}
+
+// parseExportColumns resolves a comma separated list of column names, keeping
+// the requested order. An empty list selects every column.
+func parseExportColumns(raw string) ([]exportColumn, error) {
+	if strings.TrimSpace(raw) == "" {
+		return exportColumns, nil
+	}
+
+	columns := []exportColumn{}
+
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+
+		for _, column := range exportColumns {
+			if column.name == name {
+				columns = append(columns, column)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("%w: %q", errExportUnknownColumn, name)
+		}
+	}
+
+	return columns, nil
+}
+
+// exportIncomplete marks an export that failed after its first bytes were
+// sent, both in the body, where the format allows, and in the
+// exportStatusTrailer.
+const exportIncomplete string = "export incomplete"
+
+const exportStatusTrailer string = "X-Export-Status"
+
+// exportWriter writes an export. Close completes the file; Abort instead ends
+// it with a record saying it is incomplete, so a failure that happens once
+// the response is under way is not mistaken for the whole export.
+type exportWriter interface {
+	WriteHeader(columns []exportColumn) error
+	WriteRow(values []any) error
+	Flush() error
+	Close() error
+	Abort() error
+}
+
+type exportFormat struct {
+	contentType string
+	extension   string
+	newWriter   func(io.Writer) exportWriter
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv": {
+		contentType: "text/csv; charset=utf-8",
+		extension:   "csv",
+		newWriter:   func(w io.Writer) exportWriter { return &csvExportWriter{writer: csv.NewWriter(w)} },
+	},
+	"ndjson": {
+		contentType: "application/x-ndjson",
+		extension:   "ndjson",
+		newWriter:   func(w io.Writer) exportWriter { return &ndjsonExportWriter{encoder: json.NewEncoder(w)} },
+	},
+	"xlsx": {
+		contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		extension:   "xlsx",
+		newWriter:   func(w io.Writer) exportWriter { return &xlsxExportWriter{archive: zip.NewWriter(w)} },
+	},
+}
+
+func parseExportFormat(raw string) (exportFormat, error) {
+	if raw == "" {
+		raw = "csv"
+	}
+
+	format, ok := exportFormats[raw]
+	if !ok {
+		return format, errExportUnknownFormat
+	}
+
+	return format, nil
+}
+
+func exportValueString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+// csvCell returns value as a CSV cell. Text starting with a character a
+// spreadsheet reads as the start of a formula is prefixed with a quote, so
+// names and emails cannot inject formulas into the export.
+func csvCell(value any) string {
+	cell := exportValueString(value)
+
+	if _, ok := value.(string); ok && cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+
+	return cell
+}
+
+func (e *csvExportWriter) WriteHeader(columns []exportColumn) error {
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = column.name
+	}
+
+	return e.writer.Write(record)
+}
+
+func (e *csvExportWriter) WriteRow(values []any) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = csvCell(value)
+	}
+
+	return e.writer.Write(record)
+}
+
+func (e *csvExportWriter) Flush() error {
+	e.writer.Flush()
+
+	return e.writer.Error()
+}
+
+func (e *csvExportWriter) Close() error {
+	return e.Flush()
+}
+
+func (e *csvExportWriter) Abort() error {
+	if err := e.writer.Write([]string{"# " + exportIncomplete}); err != nil {
+		return err
+	}
+
+	return e.Flush()
+}
+
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+	names   []string
+}
+
+func (e *ndjsonExportWriter) WriteHeader(columns []exportColumn) error {
+	e.names = make([]string, len(columns))
+	for i, column := range columns {
+		e.names[i] = column.name
+	}
+
+	return nil
+}
+
+func (e *ndjsonExportWriter) WriteRow(values []any) error {
+	record := make(map[string]any, len(values))
+	for i, value := range values {
+		record[e.names[i]] = value
+	}
+
+	return e.encoder.Encode(record)
+}
+
+func (e *ndjsonExportWriter) Flush() error {
+	return nil
+}
+
+func (e *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+func (e *ndjsonExportWriter) Abort() error {
+	return e.encoder.Encode(map[string]string{"error": exportIncomplete})
+}
+
+// xlsxExportWriter produces a minimal single sheet workbook. The static parts
+// of the package are written up front so the worksheet, which is the last
+// zip entry, can be streamed row by row.
+type xlsxExportWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	row     int
+}
+
+var xlsxStaticParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="customers" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+func (e *xlsxExportWriter) WriteHeader(columns []exportColumn) error {
+	for _, part := range xlsxStaticParts {
+		w, err := e.archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.WriteString(w, part.content); err != nil {
+			return err
+		}
+	}
+
+	sheet, err := e.archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	e.sheet = sheet
+
+	_, err = io.WriteString(e.sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return err
+	}
+
+	names := make([]any, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+
+	return e.WriteRow(names)
+}
+
+func (e *xlsxExportWriter) WriteRow(values []any) error {
+	var builder strings.Builder
+
+	e.row++
+	fmt.Fprintf(&builder, `<row r="%d">`, e.row)
+
+	for _, value := range values {
+		switch v := value.(type) {
+		case int:
+			fmt.Fprintf(&builder, `<c><v>%d</v></c>`, v)
+		default:
+			builder.WriteString(`<c t="inlineStr"><is><t>`)
+			xml.EscapeText(&builder, []byte(exportValueString(v)))
+			builder.WriteString(`</t></is></c>`)
+		}
+	}
+
+	builder.WriteString(`</row>`)
+
+	_, err := io.WriteString(e.sheet, builder.String())
+
+	return err
+}
+
+func (e *xlsxExportWriter) Flush() error {
+	return e.archive.Flush()
+}
+
+func (e *xlsxExportWriter) Close() error {
+	if e.sheet != nil {
+		if _, err := io.WriteString(e.sheet, `</sheetData></worksheet>`); err != nil {
+			return err
+		}
+	}
+
+	return e.archive.Close()
+}
+
+// Abort leaves the archive without its central directory, which no
+// spreadsheet opens, rather than closing it into a workbook that looks
+// whole.
+func (e *xlsxExportWriter) Abort() error {
+	return e.archive.Flush()
+}
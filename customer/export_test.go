@@ -0,0 +1,204 @@
+package customer
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExportFormat(t *testing.T) {
+	format, err := parseExportFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, "csv", format.extension)
+
+	for _, name := range []string{"csv", "ndjson", "xlsx"} {
+		format, err := parseExportFormat(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, format.extension)
+		assert.NotEmpty(t, format.contentType)
+	}
+
+	_, err = parseExportFormat("pdf")
+	assert.ErrorIs(t, err, errExportUnknownFormat)
+
+	_, err = parseExportFormat("CSV")
+	assert.ErrorIs(t, err, errExportUnknownFormat)
+}
+
+func TestParseExportColumns(t *testing.T) {
+	names := func(columns []exportColumn) []string {
+		out := []string{}
+		for _, column := range columns {
+			out = append(out, column.name)
+		}
+
+		return out
+	}
+
+	columns, err := parseExportColumns(" ")
+	require.NoError(t, err)
+	assert.Equal(t, names(exportColumns), names(columns))
+
+	columns, err = parseExportColumns("email, id,last_name")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"email", "id", "last_name"}, names(columns))
+
+	_, err = parseExportColumns("id,password")
+	assert.ErrorIs(t, err, errExportUnknownColumn)
+	assert.ErrorContains(t, err, `"password"`)
+
+	_, err = parseExportColumns("id,")
+	assert.ErrorIs(t, err, errExportUnknownColumn)
+}
+
+// writeExport runs customers through the writer of format the way GetExport
+// does and returns what it wrote.
+func writeExport(t *testing.T, format string, columns []exportColumn, customers ...modelExport) []byte {
+	var out bytes.Buffer
+
+	exportFormat, err := parseExportFormat(format)
+	require.NoError(t, err)
+
+	writer := exportFormat.newWriter(&out)
+	require.NoError(t, writer.WriteHeader(columns))
+
+	for _, customer := range customers {
+		values := make([]any, len(columns))
+		for i, column := range columns {
+			values[i] = column.value(customer)
+		}
+
+		require.NoError(t, writer.WriteRow(values))
+		require.NoError(t, writer.Flush())
+	}
+
+	require.NoError(t, writer.Close())
+
+	return out.Bytes()
+}
+
+var exportCustomers = []modelExport{
+	{Id: 1, Email: "mary@example.com", FirstName: "Mary", LastName: "Smith", CreatedAt: time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)},
+	{Id: 2, Email: "pat@example.com", FirstName: "Patricia", LastName: `O'Brien, "Pat" <Jr>`},
+}
+
+func TestCSVCell(t *testing.T) {
+	for value, want := range map[any]string{
+		`=HYPERLINK("http://evil")`: `'=HYPERLINK("http://evil")`,
+		"+1-555":                    "'+1-555",
+		"-2+3":                      "'-2+3",
+		"@SUM(A1)":                  "'@SUM(A1)",
+		"\t=1":                      "'\t=1",
+		"Smith":                     "Smith",
+		"mary=1@example.com":        "mary=1@example.com",
+		"":                          "",
+		-1:                          "-1",
+	} {
+		assert.Equal(t, want, csvCell(value), "%v", value)
+	}
+}
+
+func TestExportWriterAbort(t *testing.T) {
+	columns, err := parseExportColumns("id")
+	require.NoError(t, err)
+
+	abort := func(format string) []byte {
+		var out bytes.Buffer
+
+		exportFormat, err := parseExportFormat(format)
+		require.NoError(t, err)
+
+		writer := exportFormat.newWriter(&out)
+		require.NoError(t, writer.WriteHeader(columns))
+		require.NoError(t, writer.WriteRow([]any{1}))
+		require.NoError(t, writer.Abort())
+
+		return out.Bytes()
+	}
+
+	assert.Equal(t, "id\n1\n# export incomplete\n", string(abort("csv")))
+	assert.Equal(t, "{\"id\":1}\n{\"error\":\"export incomplete\"}\n", string(abort("ndjson")))
+
+	content := abort("xlsx")
+	_, err = zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	assert.Error(t, err, "an aborted workbook should not open")
+}
+
+func TestCSVExportWriter(t *testing.T) {
+	columns, err := parseExportColumns("id,last_name,created_at")
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(bytes.NewReader(writeExport(t, "csv", columns, exportCustomers...))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"id", "last_name", "created_at"},
+		{"1", "Smith", "2024-05-01T08:30:00Z"},
+		{"2", `O'Brien, "Pat" <Jr>`, ""},
+	}, records)
+}
+
+func TestCSVExportWriterEscapesFormulas(t *testing.T) {
+	columns, err := parseExportColumns("id,first_name,email")
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(bytes.NewReader(writeExport(t, "csv", columns, modelExport{Id: 3, FirstName: "=1+1", Email: "@evil@example.com"}))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "'=1+1", "'@evil@example.com"}, records[1])
+}
+
+func TestNDJSONExportWriter(t *testing.T) {
+	columns, err := parseExportColumns("id,email")
+	require.NoError(t, err)
+
+	lines := []map[string]any{}
+	scanner := bufio.NewScanner(bytes.NewReader(writeExport(t, "ndjson", columns, exportCustomers...)))
+	for scanner.Scan() {
+		line := map[string]any{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+
+	assert.Equal(t, []map[string]any{
+		{"id": float64(1), "email": "mary@example.com"},
+		{"id": float64(2), "email": "pat@example.com"},
+	}, lines)
+}
+
+func TestXLSXExportWriter(t *testing.T) {
+	columns, err := parseExportColumns("id,last_name")
+	require.NoError(t, err)
+
+	content := writeExport(t, "xlsx", columns, exportCustomers...)
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+
+	parts := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+
+		part, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		reader.Close()
+
+		parts[file.Name] = string(part)
+	}
+
+	for _, part := range xlsxStaticParts {
+		assert.Equal(t, part.content, parts[part.name])
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<row r="1"><c t="inlineStr"><is><t>id</t></is></c><c t="inlineStr"><is><t>last_name</t></is></c></row>`)
+	assert.Contains(t, sheet, `<row r="2"><c><v>1</v></c><c t="inlineStr"><is><t>Smith</t></is></c></row>`)
+	assert.Contains(t, sheet, `<row r="3"><c><v>2</v></c><c t="inlineStr"><is><t>O&#39;Brien, &#34;Pat&#34; &lt;Jr&gt;</t></is></c></row>`)
+	assert.Regexp(t, `</row></sheetData></worksheet>$`, sheet)
+}
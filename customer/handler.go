@@ -2,131 +2,226 @@ package customer
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/mmiftahrzki/customer/customer/address"
+	"github.com/mmiftahrzki/customer/database"
 	"github.com/mmiftahrzki/customer/logger"
+	"github.com/mmiftahrzki/customer/requests"
 	"github.com/mmiftahrzki/customer/responses"
 	"github.com/sirupsen/logrus"
 )
 
+const defaultPageSize int = 25
+
+// maxBodyBytes caps the body of requests carrying a single customer, address
+// or merge; batches get maxBatchBodyBytes.
+const (
+	maxBodyBytes      int64 = 2 << 10
+	maxBatchBodyBytes int64 = 256 << 10
+)
+
 type handler struct {
-	service service
-	log     *logrus.Entry
+	service  service
+	pageSize *atomic.Int64
+	log      *logrus.Entry
 }
 
 func newHandler(svc service) handler {
 	handler := handler{
-		service: svc,
-		log:     logger.GetLogger().WithField("component", "customerHandler"),
+		service:  svc,
+		pageSize: &atomic.Int64{},
+		log:      logger.GetLogger().WithField("component", "customerHandler"),
 	}
+	handler.pageSize.Store(int64(defaultPageSize))
 
 	return handler
 }
 
-func timeoutMiddleware(w http.ResponseWriter, r *http.Request) {
-	var queryTimeout string
-	var timeoutValue int
-	var timeoutDuration time.Duration
-
-	queryTimeout = r.URL.Query().Get("timeout")
-
-	if queryTimeout != "" {
-		var strConvErr error
-
-		timeoutValue, strConvErr = strconv.Atoi(queryTimeout)
-		if strConvErr != nil {
-			responses.Error(w, http.StatusUnprocessableEntity, "invalid timeout duration value")
+// statusFromError maps an error returned by the service, or met reading the
+// request, to the status code and message reported to the client.
+func statusFromError(err error) (int, string) {
+	var decodeErr *requests.DecodeError
+
+	switch {
+	case errors.As(err, &decodeErr):
+		return decodeErr.Status, decodeErr.Message
+	case errors.Is(err, errBatchTooLarge):
+		return http.StatusRequestEntityTooLarge, err.Error()
+	case errors.Is(err, errCustomerAlreadyExists), errors.Is(err, errCustomerAlreadyErased):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, errCustomerNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, errInvalidCustomerAddressMismatch):
+		return http.StatusUnprocessableEntity, err.Error()
+	case errors.Is(err, errCustomerFirstNameNull),
+		errors.Is(err, errCustomerLastNameNull),
+		errors.Is(err, errCustomerEmailInvalid),
+		errors.Is(err, errBatchUnknownOp),
+		errors.Is(err, errBatchMissingId),
+		errors.Is(err, errBatchInvalidData),
+		errors.Is(err, errMergeInvalidIds),
+		errors.Is(err, errMergeInvalidAddress),
+		errors.Is(err, errErasureReasonTooLong),
+		errors.Is(err, errBatchEmpty),
+		errors.Is(err, errExportUnknownFormat),
+		errors.Is(err, errExportUnknownColumn),
+		len(requests.FieldErrors(err)) > 0:
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, errBatchRolledBack), errors.Is(err, errBatchNotApplied):
+		return http.StatusFailedDependency, err.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "request timed out"
+	case database.IsRetryable(err):
+		return http.StatusServiceUnavailable, "database is busy, try again"
+	default:
+		return http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+	}
+}
 
-			return
-		}
+// problemFromError is statusFromError as a problem detail, listing the
+// fields to blame when err names any.
+func problemFromError(err error) responses.Problem {
+	code, message := statusFromError(err)
+	problem := responses.Problem{Status: code, Detail: message}
 
-		timeoutDuration = time.Duration(timeoutValue) * time.Millisecond
+	var decodeErr *requests.DecodeError
+	if errors.As(err, &decodeErr) && decodeErr.Field != "" {
+		problem.Errors = append(problem.Errors, responses.FieldError{Field: decodeErr.Field, Message: decodeErr.Message})
+	}
 
-		if timeoutDuration > time.Duration(0) {
-			if timeoutDuration > 30000*time.Millisecond {
-				timeoutDuration = 30000 * time.Millisecond
-			}
+	for _, fieldErr := range requests.FieldErrors(err) {
+		problem.Errors = append(problem.Errors, responses.FieldError{Field: fieldErr.Field, Message: fieldErr.Error()})
+	}
 
-			ctxWithTimeout, cancel := context.WithTimeout(r.Context(), timeoutDuration)
-			defer cancel()
+	return problem
+}
 
-			r = r.WithContext(ctxWithTimeout)
-		}
+// fail answers r with the problem err maps to, logging the errors that are
+// the server's fault.
+func (h *handler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	problem := problemFromError(err)
+	if problem.Status == http.StatusInternalServerError {
+		logger.WithContext(r.Context(), h.log).Error(err)
 	}
+
+	responses.WithProblem(w, r, problem)
 }
 
 func (h *handler) PostSingle(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
-	contentLenStr := r.Header.Get("Content-Length")
-	contentLen, err := strconv.Atoi(contentLenStr)
+	payload := modelCreate{}
+	err := requests.DecodeJson(w, r, &payload, maxBodyBytes)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		h.fail(w, r, err)
 
 		return
 	}
 
-	if contentLen == 0 {
-		w.WriteHeader(http.StatusBadRequest)
+	err = payload.validate()
+	if err != nil {
+		h.fail(w, r, err)
 
 		return
 	}
 
-	if contentLen > 2048 {
-		responses.Error(w, http.StatusRequestEntityTooLarge, "content length cannot be more than 2048")
+	err = h.service.CreateNewSingle(r.Context(), payload)
+	if err != nil {
+		h.fail(w, r, err)
 
 		return
 	}
 
-	payload := modelCreate{}
-	decoder := json.NewDecoder(r.Body)
-	err = decoder.Decode(&payload)
+	customersCreated.Inc()
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *handler) PostBatch(w http.ResponseWriter, r *http.Request) {
+	log := logger.WithContext(r.Context(), h.log)
+
+	var res responses.GetMultipleResponse[modelBatchResult]
+
+	defer r.Body.Close()
+
+	atomic := r.URL.Query().Get("atomic") == "true"
+
+	operations := []modelBatchOperation{}
+	err := requests.DecodeJson(w, r, &operations, maxBatchBodyBytes)
 	if err != nil {
-		h.log.Error(err)
+		h.fail(w, r, err)
+
+		return
+	}
 
-		w.WriteHeader(http.StatusBadRequest)
+	if len(operations) == 0 {
+		h.fail(w, r, errBatchEmpty)
 
 		return
 	}
 
-	err = h.service.CreateNewSingle(r.Context(), payload)
-	if err != nil {
-		if errors.Is(err, errCustomerAlreadyExists) {
-			responses.WithJson(w, http.StatusConflict, err.Error())
+	if len(operations) > batchLimit {
+		h.fail(w, r, errBatchTooLarge)
 
-			return
+		return
+	}
+
+	errs := h.service.RunBatch(r.Context(), operations, atomic)
+
+	code := http.StatusOK
+	res.Data = make([]modelBatchResult, len(operations))
+
+	for i, operation := range operations {
+		result := modelBatchResult{Index: i, Op: operation.Op, Id: operation.Id}
+
+		switch {
+		case errs[i] != nil:
+			result.Status, result.Error = statusFromError(errs[i])
+
+			if result.Status == http.StatusInternalServerError {
+				log.Error(errs[i])
+			}
+		case operation.Op == batchOpCreate:
+			result.Status = http.StatusCreated
+			customersCreated.Inc()
+		case operation.Op == batchOpDelete:
+			result.Status = http.StatusNoContent
+			customersDeleted.Inc()
+		default:
+			result.Status = http.StatusOK
+			customersUpdated.Inc()
 		}
 
-		h.log.Error(err)
+		if errs[i] != nil {
+			if atomic && result.Status != http.StatusFailedDependency {
+				code = result.Status
+			}
 
-		w.WriteHeader(http.StatusInternalServerError)
+			if !atomic {
+				code = http.StatusMultiStatus
+			}
+		}
 
-		return
+		res.Data[i] = result
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	responses.WithJson(w, code, res)
 }
 
 func (h *handler) GetMultiple(w http.ResponseWriter, r *http.Request) {
 	var res responses.GetMultipleResponse[modelRead]
+	log := logger.WithContext(r.Context(), h.log)
+	limit := int(h.pageSize.Load())
 
-	customers, svcErr := h.service.GetMultiple(r.Context())
+	customers, svcErr := h.service.GetMultiple(r.Context(), limit)
 	if svcErr != nil {
-		if errors.Is(svcErr, context.DeadlineExceeded) {
-			responses.Error(w, http.StatusServiceUnavailable, "server took too long to respond")
-
-			return
-		}
-
-		h.log.Error(svcErr)
-
-		w.WriteHeader(http.StatusInternalServerError)
+		h.fail(w, r, svcErr)
 
 		return
 	}
@@ -141,32 +236,120 @@ func (h *handler) GetMultiple(w http.ResponseWriter, r *http.Request) {
 
 	responses.WithJson(w, http.StatusOK, res)
 
-	h.log.Info("customers data retrieved successfully")
+	log.Info("customers data retrieved successfully")
 }
 
-func (h *handler) GetSingleById(w http.ResponseWriter, r *http.Request) {
-	var res responses.GetSingleResponse[modelRead]
+func (h *handler) GetExport(w http.ResponseWriter, r *http.Request) {
+	log := logger.WithContext(r.Context(), h.log)
 
-	id, err := strconv.Atoi(r.PathValue("id"))
+	const flushEvery int = 100
+
+	format, err := parseExportFormat(r.URL.Query().Get("format"))
 	if err != nil {
-		h.log.Error(err)
+		h.fail(w, r, err)
 
-		responses.Error(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	columns, err := parseExportColumns(r.URL.Query().Get("columns"))
+	if err != nil {
+		h.fail(w, r, err)
 
 		return
 	}
 
-	customer, err := h.service.GetSingleById(r.Context(), id)
+	// the export outlives the server's write timeout; the request context
+	// still ends the query as soon as the client goes away.
+	controller := http.NewResponseController(w)
+	controller.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="customers.%s"`, format.extension))
+	w.Header().Set("Trailer", exportStatusTrailer)
+	w.WriteHeader(http.StatusOK)
+
+	writer := format.newWriter(w)
+
+	// the status is only known once the body is written, so it follows as a
+	// trailer; failures are also marked in the body for clients ignoring it.
+	abort := func(err error) {
+		log.Error(err)
+
+		w.Header().Set(exportStatusTrailer, exportIncomplete)
+		if err := writer.Abort(); err != nil {
+			log.Error(err)
+		}
+	}
+
+	err = writer.WriteHeader(columns)
 	if err != nil {
-		if errors.Is(err, errCustomerNotFound) {
-			responses.Error(w, http.StatusNotFound, err.Error())
+		abort(err)
+
+		return
+	}
+
+	rows := 0
+	err = h.service.Export(r.Context(), func(customer modelExport) error {
+		values := make([]any, len(columns))
+		for i, column := range columns {
+			values[i] = column.value(customer)
+		}
+
+		if err := writer.WriteRow(values); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%flushEvery == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+
+			controller.Flush()
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Infof("customer export aborted by client after %d rows", rows)
 
 			return
 		}
 
-		h.log.Error(err)
+		abort(fmt.Errorf("customer export failed after %d rows: %w", rows, err))
+
+		return
+	}
+
+	err = writer.Close()
+	if err != nil {
+		abort(err)
+
+		return
+	}
+
+	w.Header().Set(exportStatusTrailer, "complete")
+
+	log.Infof("%d customers exported as %s", rows, format.extension)
+}
+
+func (h *handler) GetSingleById(w http.ResponseWriter, r *http.Request) {
+	var res responses.GetSingleResponse[modelRead]
+	log := logger.WithContext(r.Context(), h.log)
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error(err)
+
+		responses.Error(w, r, http.StatusBadRequest, "invalid id")
 
-		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	customer, err := h.service.GetSingleById(r.Context(), id)
+	if err != nil {
+		h.fail(w, r, err)
 
 		return
 	}
@@ -178,21 +361,22 @@ func (h *handler) GetSingleById(w http.ResponseWriter, r *http.Request) {
 
 func (h *handler) GetMultipleNext(w http.ResponseWriter, r *http.Request) {
 	var res responses.GetMultipleResponse[modelRead]
+	log := logger.WithContext(r.Context(), h.log)
 
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		h.log.Error(err)
+		log.Error(err)
 
-		responses.Error(w, http.StatusBadRequest, "invalid id")
+		responses.Error(w, r, http.StatusBadRequest, "invalid id")
 
 		return
 	}
 
-	customers, err := h.service.GetMultipleNext(r.Context(), id)
-	if err != nil {
-		h.log.Error(err)
+	limit := int(h.pageSize.Load())
 
-		w.WriteHeader(http.StatusInternalServerError)
+	customers, err := h.service.GetMultipleNext(r.Context(), id, limit)
+	if err != nil {
+		h.fail(w, r, err)
 
 		return
 	}
@@ -210,21 +394,22 @@ func (h *handler) GetMultipleNext(w http.ResponseWriter, r *http.Request) {
 
 func (h *handler) GetMultiplePrev(w http.ResponseWriter, r *http.Request) {
 	var res responses.GetMultipleResponse[modelRead]
+	log := logger.WithContext(r.Context(), h.log)
 
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		h.log.Error(err)
+		log.Error(err)
 
-		responses.Error(w, http.StatusBadRequest, "invalid id")
+		responses.Error(w, r, http.StatusBadRequest, "invalid id")
 
 		return
 	}
 
-	customers, err := h.service.GetMultiplePrev(r.Context(), id)
-	if err != nil {
-		h.log.Error(err)
+	limit := int(h.pageSize.Load())
 
-		w.WriteHeader(http.StatusInternalServerError)
+	customers, err := h.service.GetMultiplePrev(r.Context(), id, limit)
+	if err != nil {
+		h.fail(w, r, err)
 
 		return
 	}
@@ -249,119 +434,227 @@ func (h *handler) GetMultiplePrev(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *handler) PutSingleById(w http.ResponseWriter, r *http.Request) {
+	log := logger.WithContext(r.Context(), h.log)
+
 	defer r.Body.Close()
 
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		h.log.Error(err)
+		log.Error(err)
 
-		responses.Error(w, http.StatusBadRequest, "invalid id")
+		responses.Error(w, r, http.StatusBadRequest, "invalid id")
 
 		return
 	}
 
 	payload := modelUpdate{}
-	err = json.NewDecoder(r.Body).Decode(&payload)
+	err = requests.DecodeJson(w, r, &payload, maxBodyBytes)
 	if err != nil {
-		h.log.Error(err)
-
-		w.WriteHeader(http.StatusBadRequest)
+		h.fail(w, r, err)
 
 		return
 	}
 
-	err = h.service.ModifySingleById(r.Context(), id, payload)
+	err = payload.validate()
 	if err != nil {
-		if errors.Is(err, errCustomerNotFound) {
-			responses.Error(w, http.StatusUnprocessableEntity, err.Error())
-
-			return
-		}
+		h.fail(w, r, err)
 
-		h.log.Error(err)
+		return
+	}
 
-		w.WriteHeader(http.StatusInternalServerError)
+	err = h.service.ModifySingleById(r.Context(), id, payload)
+	if err != nil {
+		h.fail(w, r, err)
 
 		return
 	}
 
+	customersUpdated.Inc()
+
 	w.WriteHeader(http.StatusOK)
 }
 
 func (h *handler) DeleteSingleById(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		responses.Error(w, http.StatusBadRequest, "invalid id")
+		responses.Error(w, r, http.StatusBadRequest, "invalid id")
 
 		return
 	}
 
 	err = h.service.DeleteSingleById(r.Context(), id)
 	if err != nil {
-		h.log.Error(err)
-
-		w.WriteHeader(http.StatusInternalServerError)
+		h.fail(w, r, err)
 
 		return
 	}
 
+	customersDeleted.Inc()
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *handler) GetSingleAndUpdateAddressById(w http.ResponseWriter, r *http.Request) {
+	log := logger.WithContext(r.Context(), h.log)
+
 	defer r.Body.Close()
 
 	var err error
 
 	customerId, err := strconv.Atoi(r.PathValue("customer_id"))
 	if err != nil {
-		h.log.Error(err)
+		log.Error(err)
 
-		responses.Error(w, http.StatusBadRequest, "invalid id")
+		responses.Error(w, r, http.StatusBadRequest, "invalid id")
 
 		return
 	}
 
 	addressId, err := strconv.Atoi(r.PathValue("address_id"))
 	if err != nil {
-		h.log.Error(err)
+		log.Error(err)
 
-		responses.Error(w, http.StatusBadRequest, "invalid id")
+		responses.Error(w, r, http.StatusBadRequest, "invalid id")
 
 		return
 	}
 
 	payload := address.ModelUpdate{}
-	err = json.NewDecoder(r.Body).Decode(&payload)
+	err = requests.DecodeJson(w, r, &payload, maxBodyBytes)
 	if err != nil {
-		h.log.Error(err)
-
-		responses.Error(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		h.fail(w, r, err)
 
 		return
 	}
 
 	err = payload.Validate()
 	if err != nil {
-		responses.Error(w, http.StatusBadRequest, err.Error())
+		h.fail(w, r, err)
 
 		return
 	}
 
 	err = h.service.ModifySingleAddressById(r.Context(), customerId, uint16(addressId), payload)
 	if err != nil {
-		if errors.Is(err, errCustomerNotFound) || errors.Is(err, errInvalidCustomerAddressMismatch) {
-			responses.WithJson(w, http.StatusUnprocessableEntity, err.Error())
+		h.fail(w, r, err)
 
-			return
-		}
+		return
+	}
 
-		h.log.Error(err)
+	w.WriteHeader(http.StatusOK)
+}
 
-		w.WriteHeader(http.StatusInternalServerError)
+func (h *handler) GetDuplicatesById(w http.ResponseWriter, r *http.Request) {
+	var res responses.GetMultipleResponse[modelDuplicate]
+	log := logger.WithContext(r.Context(), h.log)
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error(err)
+
+		responses.Error(w, r, http.StatusBadRequest, "invalid id")
 
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	duplicates, err := h.service.FindDuplicates(r.Context(), id)
+	if err != nil {
+		h.fail(w, r, err)
+
+		return
+	}
+
+	res.Data = duplicates
+
+	responses.WithJson(w, http.StatusOK, res)
+}
+
+func (h *handler) PostMerge(w http.ResponseWriter, r *http.Request) {
+	var res responses.GetSingleResponse[modelRead]
+
+	defer r.Body.Close()
+
+	payload := modelMergeCreate{}
+	err := requests.DecodeJson(w, r, &payload, maxBodyBytes)
+	if err != nil {
+		h.fail(w, r, err)
+
+		return
+	}
+
+	survivor, err := h.service.Merge(r.Context(), payload)
+	if err != nil {
+		h.fail(w, r, err)
+
+		return
+	}
+
+	customersMerged.Inc()
+
+	res.Data = survivor
+
+	responses.WithJson(w, http.StatusOK, res)
+}
+
+func (h *handler) GetPersonalDataById(w http.ResponseWriter, r *http.Request) {
+	var res responses.GetSingleResponse[modelPersonalData]
+	log := logger.WithContext(r.Context(), h.log)
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error(err)
+
+		responses.Error(w, r, http.StatusBadRequest, "invalid id")
+
+		return
+	}
+
+	bundle, err := h.service.GetPersonalData(r.Context(), id)
+	if err != nil {
+		h.fail(w, r, err)
+
+		return
+	}
+
+	res.Data = bundle
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="customer-%d-personal-data.json"`, id))
+	responses.WithJson(w, http.StatusOK, res)
+}
+
+func (h *handler) PostEraseById(w http.ResponseWriter, r *http.Request) {
+	var res responses.GetSingleResponse[modelErasure]
+	log := logger.WithContext(r.Context(), h.log)
+
+	defer r.Body.Close()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Error(err)
+
+		responses.Error(w, r, http.StatusBadRequest, "invalid id")
+
+		return
+	}
+
+	payload := modelErasureCreate{}
+	err = requests.DecodeJson(w, r, &payload, maxBodyBytes)
+	if err != nil && !errors.Is(err, requests.ErrEmptyBody) {
+		h.fail(w, r, err)
+
+		return
+	}
+
+	erasure, err := h.service.Erase(r.Context(), id, payload)
+	if err != nil {
+		h.fail(w, r, err)
+
+		return
+	}
+
+	customersErased.Inc()
+
+	res.Data = erasure
+
+	responses.WithJson(w, http.StatusOK, res)
 }
@@ -0,0 +1,266 @@
+package customer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mmiftahrzki/customer/auth"
+	"github.com/mmiftahrzki/customer/customer/address"
+	"github.com/mmiftahrzki/customer/requests"
+	"github.com/mmiftahrzki/customer/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblemFromError(t *testing.T) {
+	long := strings.Repeat("a", 60)
+	district := strings.Repeat("d", 30)
+
+	for name, test := range map[string]struct {
+		err  error
+		want responses.Problem
+	}{
+		"not found": {
+			err:  errCustomerNotFound,
+			want: responses.Problem{Status: http.StatusNotFound, Detail: "customer not found"},
+		},
+		"conflict": {
+			err:  errCustomerAlreadyExists,
+			want: responses.Problem{Status: http.StatusConflict, Detail: "customer already exists"},
+		},
+		"deadline": {
+			err:  context.DeadlineExceeded,
+			want: responses.Problem{Status: http.StatusGatewayTimeout, Detail: "request timed out"},
+		},
+		"unexpected": {
+			err:  errors.New("connection refused"),
+			want: responses.Problem{Status: http.StatusInternalServerError, Detail: "Internal Server Error"},
+		},
+		"validation": {
+			err: modelUpdate{}.validate(),
+			want: responses.Problem{
+				Status: http.StatusBadRequest,
+				Detail: "customer first name is required; customer last name is required",
+				Errors: []responses.FieldError{
+					{Field: "first_name", Message: "customer first name is required"},
+					{Field: "last_name", Message: "customer last name is required"},
+				},
+			},
+		},
+		"address validation": {
+			err: address.ModelUpdate{Address: &long, District: &district}.Validate(),
+			want: responses.Problem{
+				Status: http.StatusBadRequest,
+				Detail: "address cannot be more than 50 characters; district name cannot be more than 20 characters",
+				Errors: []responses.FieldError{
+					{Field: "address", Message: "address cannot be more than 50 characters"},
+					{Field: "district", Message: "district name cannot be more than 20 characters"},
+				},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.want, problemFromError(test.err))
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"first_name": 1}`))
+	r.Header.Set("Content-Type", "application/json")
+	err := requests.DecodeJson(httptest.NewRecorder(), r, &modelCreate{}, maxBodyBytes)
+	assert.Equal(t, responses.Problem{
+		Status: http.StatusBadRequest,
+		Detail: `field "first_name" must be a JSON string`,
+		Errors: []responses.FieldError{{Field: "first_name", Message: `field "first_name" must be a JSON string`}},
+	}, problemFromError(err))
+
+	assert.ErrorIs(t, modelUpdate{}.validate(), errCustomerLastNameNull, "every field error should match")
+}
+
+func problem(t *testing.T, w *httptest.ResponseRecorder) responses.Problem {
+	var problem responses.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+
+	return problem
+}
+
+func fields(problem responses.Problem) []string {
+	out := []string{}
+	for _, fieldErr := range problem.Errors {
+		out = append(out, fieldErr.Field)
+	}
+
+	return out
+}
+
+func TestPostSingle(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r repo) {
+		h := newHandler(newService(r, serviceCache{}))
+
+		for name, test := range map[string]struct {
+			body   string
+			fields []string
+		}{
+			"empty":             {body: `{}`, fields: []string{"first_name", "last_name", "email"}},
+			"invalid email":     {body: `{"first_name": "Mary", "last_name": "Smith", "email": "mary"}`, fields: []string{"email"}},
+			"named email":       {body: `{"first_name": "Mary", "last_name": "Smith", "email": "Mary <mary@example.com>"}`, fields: []string{"email"}},
+			"name too long":     {body: `{"first_name": "` + strings.Repeat("a", 46) + `", "last_name": "Smith", "email": "mary@example.com"}`, fields: []string{"first_name"}},
+			"email too long":    {body: `{"first_name": "Mary", "last_name": "Smith", "email": "` + strings.Repeat("m", 39) + `@example.com"}`, fields: []string{"email"}},
+			"missing last name": {body: `{"first_name": "Mary", "email": "mary@example.com"}`, fields: []string{"last_name"}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				w := serve(h.PostSingle, http.MethodPost, "/api/customer", test.body)
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+				assert.Equal(t, test.fields, fields(problem(t, w)))
+			})
+		}
+
+		customers, err := r.SelectAll(context.Background(), 10)
+		require.NoError(t, err)
+		assert.Empty(t, customers, "nothing invalid should have been inserted")
+
+		w := serve(h.PostSingle, http.MethodPost, "/api/customer", `{"first_name": "Mary", "last_name": "Smith", "email": "mary@example.com"}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+}
+
+func TestPutSingleById(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r repo) {
+		h := newHandler(newService(r, serviceCache{}))
+		mux := http.NewServeMux()
+		mux.HandleFunc("PUT /api/customer/{id}", h.PutSingleById)
+
+		insertCustomers(t, r, modelCreate{FirstName: "Mary", LastName: "Smith", Email: "mary@example.com"})
+
+		w := serve(mux.ServeHTTP, http.MethodPut, "/api/customer/1", `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, []string{"first_name", "last_name"}, fields(problem(t, w)))
+
+		mary, err := r.SelectSingleById(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, "Mary", mary.firstName.String, "an invalid update should change nothing")
+
+		w = serve(mux.ServeHTTP, http.MethodPut, "/api/customer/1", `{"first_name": "Maria", "last_name": "Smith", "email": "mary@example.com"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		mary, err = r.SelectSingleById(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, "Maria", mary.firstName.String)
+	})
+}
+
+// serve calls handle with a JSON body as an authenticated admin and returns
+// the recorded response.
+func serve(handle http.HandlerFunc, method string, target string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r = r.WithContext(context.WithValue(r.Context(), auth.JWTContextKey, &auth.ModelClaim{Email: "admin@example.com"}))
+
+	w := httptest.NewRecorder()
+	handle(w, r)
+
+	return w
+}
+
+func batchStatuses(t *testing.T, w *httptest.ResponseRecorder) []int {
+	var res responses.GetMultipleResponse[modelBatchResult]
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	statuses := []int{}
+	for _, result := range res.Data {
+		statuses = append(statuses, result.Status)
+	}
+
+	return statuses
+}
+
+func TestPostBatch(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r repo) {
+		h := newHandler(newService(r, serviceCache{}))
+		ctx := context.Background()
+
+		insertCustomers(t, r, modelCreate{FirstName: "Mary", LastName: "Smith", Email: "mary@example.com"})
+
+		t.Run("best effort", func(t *testing.T) {
+			w := serve(h.PostBatch, http.MethodPost, "/api/customer/batch", `[
+				{"op": "create", "data": {"first_name": "Linda", "last_name": "Williams", "email": "linda@example.com"}},
+				{"op": "create", "data": {"first_name": "Mary", "last_name": "Smith", "email": "mary@example.com"}},
+				{"op": "update", "id": 999, "data": {"first_name": "Nobody", "last_name": "Here"}},
+				{"op": "rename", "id": 1},
+				{"op": "create", "data": {"first_name": "Nancy", "last_name": "", "email": "nancy"}}
+			]`)
+			assert.Equal(t, http.StatusMultiStatus, w.Code)
+			assert.Equal(t, []int{http.StatusCreated, http.StatusConflict, http.StatusNotFound, http.StatusBadRequest, http.StatusBadRequest}, batchStatuses(t, w))
+
+			linda, err := r.SelectSingleByEmail(ctx, "linda@example.com")
+			require.NoError(t, err)
+			assert.True(t, linda.id.Valid, "the successful operation should be kept")
+		})
+
+		t.Run("best effort without failures", func(t *testing.T) {
+			w := serve(h.PostBatch, http.MethodPost, "/api/customer/batch", `[
+				{"op": "update", "id": 1, "data": {"first_name": "Maria", "last_name": "Smith", "email": "mary@example.com"}}
+			]`)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, []int{http.StatusOK}, batchStatuses(t, w))
+		})
+
+		t.Run("atomic", func(t *testing.T) {
+			w := serve(h.PostBatch, http.MethodPost, "/api/customer/batch?atomic=true", `[
+				{"op": "create", "data": {"first_name": "Patricia", "last_name": "Johnson", "email": "patricia@example.com"}},
+				{"op": "create", "data": {"first_name": "Mary", "last_name": "Smith", "email": "mary@example.com"}},
+				{"op": "create", "data": {"first_name": "Barbara", "last_name": "Jones", "email": "barbara@example.com"}}
+			]`)
+			assert.Equal(t, http.StatusConflict, w.Code)
+			assert.Equal(t, []int{http.StatusFailedDependency, http.StatusConflict, http.StatusFailedDependency}, batchStatuses(t, w))
+
+			for _, email := range []string{"patricia@example.com", "barbara@example.com"} {
+				customer, err := r.SelectSingleByEmail(ctx, email)
+				require.NoError(t, err)
+				assert.False(t, customer.id.Valid, "%s should have been rolled back", email)
+			}
+		})
+
+		t.Run("atomic with an invalid operation", func(t *testing.T) {
+			w := serve(h.PostBatch, http.MethodPost, "/api/customer/batch?atomic=true", `[
+				{"op": "create", "data": {"first_name": "Patricia", "last_name": "Johnson", "email": "patricia@example.com"}},
+				{"op": "update", "data": {"first_name": "Maria", "last_name": "Smith", "email": "mary@example.com"}}
+			]`)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, []int{http.StatusFailedDependency, http.StatusBadRequest}, batchStatuses(t, w))
+
+			patricia, err := r.SelectSingleByEmail(ctx, "patricia@example.com")
+			require.NoError(t, err)
+			assert.False(t, patricia.id.Valid)
+		})
+
+		t.Run("empty", func(t *testing.T) {
+			w := serve(h.PostBatch, http.MethodPost, "/api/customer/batch", `[]`)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	})
+}
+
+func TestGetExport(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r repo) {
+		h := newHandler(newService(r, serviceCache{}))
+
+		insertCustomers(t, r, modelCreate{FirstName: "Mary", LastName: "Smith", Email: "mary@example.com"})
+
+		w := serve(h.GetExport, http.MethodGet, "/api/customer/export?format=ndjson&columns=id,email", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "{\"email\":\"mary@example.com\",\"id\":1}\n", w.Body.String())
+		assert.Equal(t, "complete", w.Result().Trailer.Get(exportStatusTrailer))
+
+		require.NoError(t, r.db.Close())
+
+		w = serve(h.GetExport, http.MethodGet, "/api/customer/export?format=ndjson&columns=id,email", "")
+		assert.Equal(t, http.StatusOK, w.Code, "the status is sent before the export fails")
+		assert.Equal(t, "{\"error\":\"export incomplete\"}\n", w.Body.String())
+		assert.Equal(t, exportIncomplete, w.Result().Trailer.Get(exportStatusTrailer))
+	})
+}
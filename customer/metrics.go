@@ -0,0 +1,13 @@
+package customer
+
+import "github.com/mmiftahrzki/customer/metrics"
+
+// Business counters are incremented by the handlers once a write has been
+// committed, so operations rolled back by an atomic batch are not counted.
+var (
+	customersCreated = metrics.GetRegistry().NewCounterVec("customers_created_total", "Customers created.")
+	customersUpdated = metrics.GetRegistry().NewCounterVec("customers_updated_total", "Customers updated.")
+	customersDeleted = metrics.GetRegistry().NewCounterVec("customers_deleted_total", "Customers deleted.")
+	customersMerged  = metrics.GetRegistry().NewCounterVec("customers_merged_total", "Duplicate customers merged into a survivor.")
+	customersErased  = metrics.GetRegistry().NewCounterVec("customers_erased_total", "Customers whose personal data was erased.")
+)
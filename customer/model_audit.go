@@ -0,0 +1,24 @@
+package customer
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	auditActionCreate        string = "create"
+	auditActionUpdate        string = "update"
+	auditActionUpdateAddress string = "update_address"
+	auditActionDelete        string = "delete"
+	auditActionMerge         string = "merge"
+	auditActionErase         string = "erase"
+)
+
+type modelAudit struct {
+	Id         int64           `json:"id"`
+	CustomerId int             `json:"customer_id"`
+	Action     string          `json:"action"`
+	Actor      string          `json:"actor"`
+	Detail     json.RawMessage `json:"detail,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
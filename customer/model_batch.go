@@ -0,0 +1,73 @@
+package customer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mmiftahrzki/customer/requests"
+)
+
+const batchLimit int = 100
+
+const (
+	batchOpCreate string = "create"
+	batchOpUpdate string = "update"
+	batchOpDelete string = "delete"
+)
+
+var errBatchEmpty = errors.New("batch must contain at least one operation")
+var errBatchTooLarge = fmt.Errorf("batch cannot contain more than %d operations", batchLimit)
+var errBatchUnknownOp = errors.New("operation must be one of create, update or delete")
+var errBatchMissingId = errors.New("operation requires an id")
+var errBatchInvalidData = errors.New("operation data is invalid")
+var errBatchRolledBack = errors.New("operation rolled back because another operation in the batch failed")
+var errBatchNotApplied = errors.New("operation not applied because another operation in the batch failed")
+
+type modelBatchOperation struct {
+	Op   string          `json:"op"`
+	Id   int             `json:"id,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+
+	create modelCreate
+	update modelUpdate
+}
+
+type modelBatchResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Id     int    `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// parse checks the operation and decodes its data into the payload the
+// matching service method expects.
+func (m *modelBatchOperation) parse() error {
+	switch m.Op {
+	case batchOpCreate:
+		if err := requests.Unmarshal(m.Data, &m.create); err != nil {
+			return fmt.Errorf("%w: %v", errBatchInvalidData, err)
+		}
+
+		return m.create.validate()
+	case batchOpUpdate:
+		if m.Id <= 0 {
+			return errBatchMissingId
+		}
+
+		if err := requests.Unmarshal(m.Data, &m.update); err != nil {
+			return fmt.Errorf("%w: %v", errBatchInvalidData, err)
+		}
+
+		return m.update.validate()
+	case batchOpDelete:
+		if m.Id <= 0 {
+			return errBatchMissingId
+		}
+	default:
+		return errBatchUnknownOp
+	}
+
+	return nil
+}
@@ -1,7 +1,46 @@
 package customer
 
+import (
+	"errors"
+	"net/mail"
+
+	"github.com/mmiftahrzki/customer/requests"
+)
+
 type modelCreate struct {
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 	Email     string `json:"email"`
 }
+
+var errCustomerEmailInvalid = errors.New("customer email must be a valid email address of at most 50 characters")
+
+func (m modelCreate) validate() error {
+	errs := requests.ValidationError{}
+
+	if m.FirstName == "" || len(m.FirstName) > 45 {
+		errs = append(errs, requests.Invalid("first_name", errCustomerFirstNameNull))
+	}
+
+	if m.LastName == "" || len(m.LastName) > 45 {
+		errs = append(errs, requests.Invalid("last_name", errCustomerLastNameNull))
+	}
+
+	if !validEmail(m.Email) {
+		errs = append(errs, requests.Invalid("email", errCustomerEmailInvalid))
+	}
+
+	return errs.Err()
+}
+
+// validEmail reports whether email is a bare address, without a display name
+// or angle brackets, that fits the email column.
+func validEmail(email string) bool {
+	if len(email) > 50 {
+		return false
+	}
+
+	address, err := mail.ParseAddress(email)
+
+	return err == nil && address.Address == email
+}
@@ -0,0 +1,62 @@
+package customer
+
+import "time"
+
+type modelExport struct {
+	Id         int       `json:"id"`
+	Email      string    `json:"email"`
+	FirstName  string    `json:"first_name"`
+	LastName   string    `json:"last_name"`
+	AddressId  int       `json:"address_id"`
+	Address    string    `json:"address"`
+	District   string    `json:"district"`
+	CityId     int       `json:"city_id"`
+	PostalCode string    `json:"postal_code"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newExportModel(modelSQL modelSQL) modelExport {
+	var customer modelExport
+
+	if modelSQL.id.Valid {
+		customer.Id = int(modelSQL.id.Int16)
+	}
+
+	if modelSQL.email.Valid {
+		customer.Email = modelSQL.email.String
+	}
+
+	if modelSQL.firstName.Valid {
+		customer.FirstName = modelSQL.firstName.String
+	}
+
+	if modelSQL.lastName.Valid {
+		customer.LastName = modelSQL.lastName.String
+	}
+
+	if modelSQL.addressId.Valid {
+		customer.AddressId = int(modelSQL.addressId.Int16)
+	}
+
+	if modelSQL.address.Address.Valid {
+		customer.Address = modelSQL.address.Address.String
+	}
+
+	if modelSQL.address.District.Valid {
+		customer.District = modelSQL.address.District.String
+	}
+
+	if modelSQL.address.CityId.Valid {
+		customer.CityId = int(modelSQL.address.CityId.Int16)
+	}
+
+	if modelSQL.address.PostalCode.Valid {
+		customer.PostalCode = modelSQL.address.PostalCode.String
+	}
+
+	if modelSQL.createdAt.Valid {
+		customer.CreatedAt = modelSQL.createdAt.Time
+	}
+
+	return customer
+}
@@ -0,0 +1,71 @@
+package customer
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/mmiftahrzki/customer/customer/duplicate"
+	"github.com/mmiftahrzki/customer/requests"
+)
+
+const (
+	mergeKeepSurvivorAddress  string = "survivor"
+	mergeKeepDuplicateAddress string = "duplicate"
+)
+
+const duplicateCandidatesLimit int = 10
+
+var errMergeInvalidIds = errors.New("survivor_id and duplicate_id must be two different customers")
+var errMergeInvalidAddress = errors.New("address must be either survivor or duplicate")
+
+type modelMergeCreate struct {
+	SurvivorId  int    `json:"survivor_id"`
+	DuplicateId int    `json:"duplicate_id"`
+	Address     string `json:"address"`
+}
+
+func (m modelMergeCreate) validate() error {
+	errs := requests.ValidationError{}
+
+	if m.SurvivorId <= 0 || m.DuplicateId <= 0 || m.SurvivorId == m.DuplicateId {
+		errs = append(errs, requests.Invalid("duplicate_id", errMergeInvalidIds))
+	}
+
+	if m.Address != "" && m.Address != mergeKeepSurvivorAddress && m.Address != mergeKeepDuplicateAddress {
+		errs = append(errs, requests.Invalid("address", errMergeInvalidAddress))
+	}
+
+	return errs.Err()
+}
+
+type modelMerge struct {
+	SurvivorId        int             `json:"survivor_id"`
+	DuplicateId       int             `json:"duplicate_id"`
+	DuplicateSnapshot json.RawMessage `json:"duplicate_snapshot"`
+	MergedBy          string          `json:"merged_by"`
+	MergedAt          time.Time       `json:"merged_at"`
+}
+
+type modelDuplicate struct {
+	Customer modelRead `json:"customer"`
+	Score    float64   `json:"score"`
+	Reasons  []string  `json:"reasons"`
+}
+
+// newDuplicateCandidate describes modelSQL to the duplicate scorer. The
+// default address is shared by every customer who never set one, so it is
+// left out rather than counted as a matching address.
+func newDuplicateCandidate(modelSQL modelSQL) duplicate.Candidate {
+	addressId := int(modelSQL.addressId.Int16)
+	if addressId == defaultAddressId {
+		addressId = 0
+	}
+
+	return duplicate.Candidate{
+		Email:     modelSQL.email.String,
+		FirstName: modelSQL.firstName.String,
+		LastName:  modelSQL.lastName.String,
+		AddressId: addressId,
+	}
+}
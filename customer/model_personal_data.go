@@ -0,0 +1,74 @@
+package customer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mmiftahrzki/customer/customer/address"
+	"github.com/mmiftahrzki/customer/requests"
+)
+
+const erasedName string = "ERASED"
+
+var errCustomerAlreadyErased = errors.New("customer personal data has already been erased")
+var errErasureReasonTooLong = errors.New("reason cannot be more than 255 characters")
+
+type modelPersonalData struct {
+	Customer    modelExport       `json:"customer"`
+	Address     address.ModelRead `json:"address"`
+	Audit       []modelAudit      `json:"audit"`
+	Merges      []modelMerge      `json:"merges"`
+	GeneratedAt time.Time         `json:"generated_at"`
+}
+
+type modelErasureCreate struct {
+	Reason string `json:"reason"`
+}
+
+func (m modelErasureCreate) validate() error {
+	if len(m.Reason) > 255 {
+		return requests.ValidationError{requests.Invalid("reason", errErasureReasonTooLong)}
+	}
+
+	return nil
+}
+
+type modelErasure struct {
+	CustomerId int       `json:"customer_id"`
+	Reason     string    `json:"reason"`
+	ErasedBy   string    `json:"erased_by"`
+	ErasedAt   time.Time `json:"erased_at"`
+}
+
+// erasedEmail is unique per customer so the email column keeps satisfying its
+// unique key once the real address is gone.
+func erasedEmail(id int) string {
+	return fmt.Sprintf("erased-%d@invalid", id)
+}
+
+func newPersonalDataAddress(modelSQL modelSQL) address.ModelRead {
+	var addressModelRead address.ModelRead
+
+	if modelSQL.address.Id.Valid {
+		addressModelRead.Id = int(modelSQL.address.Id.Int16)
+	}
+
+	if modelSQL.address.Address.Valid {
+		addressModelRead.Address = modelSQL.address.Address.String
+	}
+
+	if modelSQL.address.District.Valid {
+		addressModelRead.District = modelSQL.address.District.String
+	}
+
+	if modelSQL.address.CityId.Valid {
+		addressModelRead.CityId = int(modelSQL.address.CityId.Int16)
+	}
+
+	if modelSQL.address.PostalCode.Valid {
+		addressModelRead.PostalCode = modelSQL.address.PostalCode.String
+	}
+
+	return addressModelRead
+}
@@ -2,6 +2,8 @@ package customer
 
 import (
 	"errors"
+
+	"github.com/mmiftahrzki/customer/requests"
 )
 
 type modelUpdate struct {
@@ -14,13 +16,15 @@ var errCustomerFirstNameNull = errors.New("customer first name is required")
 var errCustomerLastNameNull = errors.New("customer last name is required")
 
 func (m modelUpdate) validate() error {
+	errs := requests.ValidationError{}
+
 	if m.FirstName == nil || len(*m.FirstName) > 45 {
-		return errCustomerFirstNameNull
+		errs = append(errs, requests.Invalid("first_name", errCustomerFirstNameNull))
 	}
 
 	if m.LastName == nil || len(*m.LastName) > 45 {
-		return errCustomerLastNameNull
+		errs = append(errs, requests.Invalid("last_name", errCustomerLastNameNull))
 	}
 
-	return nil
+	return errs.Err()
 }
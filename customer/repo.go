@@ -10,25 +10,62 @@ import (
 
 	"github.com/mmiftahrzki/customer/auth"
 	"github.com/mmiftahrzki/customer/customer/address"
+	"github.com/mmiftahrzki/customer/database"
+	"github.com/mmiftahrzki/customer/encryption"
 	"github.com/mmiftahrzki/customer/logger"
+	"github.com/mmiftahrzki/customer/tracing"
 	"github.com/sirupsen/logrus"
 )
 
-const limit int = 25
-
 type repo struct {
-	db  *sql.DB
-	log *logrus.Entry
+	db     *database.DB
+	fields *encryption.Fields
+	log    *logrus.Entry
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
-func newRepo(db *sql.DB) repo {
+func newRepo(db *database.DB, fields *encryption.Fields) repo {
 	return repo{
-		db:  db,
-		log: logger.GetLogger().WithField("component", "customerRepo"),
+		db:     db,
+		fields: fields,
+		log:    logger.GetLogger().WithField("component", "customerRepo"),
+	}
+}
+
+// conn returns what a write should run on: the transaction of the unit of
+// work carried by ctx, if any, so reads and writes made inside it see each
+// other and commit together, or the primary. The write is recorded so the reads following it do not go to
+// a replica.
+func (r *repo) conn(ctx context.Context) querier {
+	database.MarkWritten(ctx)
+
+	if tx, ok := database.TxFromContext(ctx); ok {
+		return tx
+	}
+
+	return r.db
+}
+
+// reader returns what a read should run on: the transaction carried by ctx,
+// if any, or a replica unless ctx has written.
+func (r *repo) reader(ctx context.Context) querier {
+	if tx, ok := database.TxFromContext(ctx); ok {
+		return tx
 	}
+
+	return r.db.Reader(ctx)
 }
 
-func (r *repo) SelectAll(ctx context.Context) ([]modelSQL, error) {
+func (r *repo) SelectAll(ctx context.Context, limit int) ([]modelSQL, error) {
+	ctx, span := tracing.Start(ctx, "customer.repo.SelectAll")
+	defer span.End()
+
 	var sqlModel modelSQL
 	var sqlModels []modelSQL
 	const sqlQuery string = `SELECT a.id,
@@ -46,16 +83,15 @@ func (r *repo) SelectAll(ctx context.Context) ([]modelSQL, error) {
 		FROM customer a
 			JOIN address b ON b.id = a.address_id
 		WHERE a.active = true
-		ORDER BY a.id ASC
-		LIMIT ?`
+		ORDER BY a.id ASC`
 
 	select {
 	case <-ctx.Done():
-		r.log.Info("deadline exceeded from repo layer")
+		logger.WithContext(ctx, r.log).Info("deadline exceeded from repo layer")
 
 		return sqlModels, ctx.Err()
 	default:
-		rows, sqlErr := r.db.QueryContext(ctx, sqlQuery, limit+1)
+		rows, sqlErr := r.reader(ctx).QueryContext(ctx, r.db.Dialect.Paginate(sqlQuery, false), limit+1)
 		if sqlErr != nil {
 			return sqlModels, sqlErr
 		}
@@ -80,16 +116,89 @@ func (r *repo) SelectAll(ctx context.Context) ([]modelSQL, error) {
 				return sqlModels, rowScanErr
 			}
 
+			if decryptErr := r.decryptModel(&sqlModel); decryptErr != nil {
+				return sqlModels, decryptErr
+			}
+
 			sqlModels = append(sqlModels, sqlModel)
 		}
 
-		r.log.Info("customers data successfully retrieved from database")
+		logger.WithContext(ctx, r.log).Info("customers data successfully retrieved from database")
 
 		return sqlModels, nil
 	}
 }
 
-func (r *repo) SelectAllPrev(ctx context.Context, customer modelRead) (modelSQLs []modelSQL, err error) {
+// SelectAllStream walks every active customer in the same order as SelectAll
+// without a limit, handing each row to fn as soon as it is scanned so callers
+// never hold the whole result set in memory.
+func (r *repo) SelectAllStream(ctx context.Context, fn func(modelSQL) error) error {
+	ctx, span := tracing.Start(ctx, "customer.repo.SelectAllStream")
+	defer span.End()
+
+	var modelSQL modelSQL
+	const sqlQuery string = `SELECT a.id,
+			a.email,
+			a.first_name,
+			a.last_name,
+			a.address_id,
+			a.active,
+			a.created_at,
+			b.id,
+			b.address,
+			b.district,
+			b.city_id,
+			b.postal_code
+		FROM customer a
+			JOIN address b ON b.id = a.address_id
+		WHERE a.active = true
+		ORDER BY a.id ASC`
+
+	rows, err := r.reader(ctx).QueryContext(ctx, sqlQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		err = rows.Scan(
+			&modelSQL.id,
+			&modelSQL.email,
+			&modelSQL.firstName,
+			&modelSQL.lastName,
+			&modelSQL.addressId,
+			&modelSQL.active,
+			&modelSQL.createdAt,
+			&modelSQL.address.Id,
+			&modelSQL.address.Address,
+			&modelSQL.address.District,
+			&modelSQL.address.CityId,
+			&modelSQL.address.PostalCode,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err = r.decryptModel(&modelSQL); err != nil {
+			return err
+		}
+
+		if err = fn(modelSQL); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r *repo) SelectAllPrev(ctx context.Context, customer modelRead, limit int) (modelSQLs []modelSQL, err error) {
+	ctx, span := tracing.Start(ctx, "customer.repo.SelectAllPrev")
+	defer span.End()
+
 	var modelSQL modelSQL
 	const sqlQuery string = `SELECT a.id,
 			a.email,
@@ -107,10 +216,9 @@ func (r *repo) SelectAllPrev(ctx context.Context, customer modelRead) (modelSQLs
 			JOIN address b ON b.id = a.address_id
 		WHERE a.active = TRUE
 			AND a.id < ?
-		ORDER BY a.id DESC
-      LIMIT ?`
+		ORDER BY a.id DESC`
 
-	rows, err := r.db.QueryContext(ctx, sqlQuery, customer.Id, limit)
+	rows, err := r.reader(ctx).QueryContext(ctx, r.db.Dialect.Paginate(sqlQuery, false), customer.Id, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -136,13 +244,20 @@ func (r *repo) SelectAllPrev(ctx context.Context, customer modelRead) (modelSQLs
 			return nil, err
 		}
 
+		if err = r.decryptModel(&modelSQL); err != nil {
+			return nil, err
+		}
+
 		modelSQLs = append(modelSQLs, modelSQL)
 	}
 
 	return modelSQLs, nil
 }
 
-func (r *repo) SelectAllNext(ctx context.Context, customer modelRead) (modelSQLs []modelSQL, err error) {
+func (r *repo) SelectAllNext(ctx context.Context, customer modelRead, limit int) (modelSQLs []modelSQL, err error) {
+	ctx, span := tracing.Start(ctx, "customer.repo.SelectAllNext")
+	defer span.End()
+
 	var modelSQL modelSQL
 	const sqlQuery string = `SELECT a.id,
 			a.email,
@@ -160,10 +275,9 @@ func (r *repo) SelectAllNext(ctx context.Context, customer modelRead) (modelSQLs
 			JOIN address b ON b.id = a.address_id
 		WHERE a.active = TRUE
 			AND a.id > ?
-		ORDER BY a.id ASC
-		LIMIT ?`
+		ORDER BY a.id ASC`
 
-	rows, err := r.db.QueryContext(ctx, sqlQuery, customer.Id, limit+1)
+	rows, err := r.reader(ctx).QueryContext(ctx, r.db.Dialect.Paginate(sqlQuery, false), customer.Id, limit+1)
 	if err != nil {
 		return nil, err
 	}
@@ -189,6 +303,10 @@ func (r *repo) SelectAllNext(ctx context.Context, customer modelRead) (modelSQLs
 			return nil, err
 		}
 
+		if err = r.decryptModel(&modelSQL); err != nil {
+			return nil, err
+		}
+
 		modelSQLs = append(modelSQLs, modelSQL)
 	}
 
@@ -196,6 +314,9 @@ func (r *repo) SelectAllNext(ctx context.Context, customer modelRead) (modelSQLs
 }
 
 func (r *repo) SelectSingleById(ctx context.Context, id int) (modelSQL, error) {
+	ctx, span := tracing.Start(ctx, "customer.repo.SelectSingleById")
+	defer span.End()
+
 	var modelSQL modelSQL
 	const sqlQuery string = `
 		SELECT a.id,
@@ -214,7 +335,7 @@ func (r *repo) SelectSingleById(ctx context.Context, id int) (modelSQL, error) {
 			JOIN address b ON b.id = a.address_id
 		WHERE a.active = true
 			AND a.id=?`
-	rows, err := r.db.QueryContext(ctx, sqlQuery, id)
+	rows, err := r.reader(ctx).QueryContext(ctx, sqlQuery, id)
 	if err != nil {
 		return modelSQL, err
 	}
@@ -238,14 +359,37 @@ func (r *repo) SelectSingleById(ctx context.Context, id int) (modelSQL, error) {
 		if err != nil {
 			return modelSQL, err
 		}
+
+		if err = r.decryptModel(&modelSQL); err != nil {
+			return modelSQL, err
+		}
 	}
 
 	return modelSQL, nil
 }
 
 func (r *repo) UpdateSingleById(ctx context.Context, id int, payload modelUpdate) error {
-	const sqlQuery string = "UPDATE customer SET first_name=?, last_name=?, email=? WHERE id=?"
-	_, dbErr := r.db.ExecContext(ctx, sqlQuery, payload.FirstName, payload.LastName, payload.Email, id)
+	ctx, span := tracing.Start(ctx, "customer.repo.UpdateSingleById")
+	defer span.End()
+
+	const sqlQuery string = "UPDATE customer SET first_name=?, last_name=?, email=?, email_bidx=? WHERE id=?"
+
+	firstName, err := r.encryptPtr(columnCustomerFirstName, payload.FirstName)
+	if err != nil {
+		return err
+	}
+
+	lastName, err := r.encryptPtr(columnCustomerLastName, payload.LastName)
+	if err != nil {
+		return err
+	}
+
+	email, err := r.encryptPtr(columnCustomerEmail, payload.Email)
+	if err != nil {
+		return err
+	}
+
+	_, dbErr := r.conn(ctx).ExecContext(ctx, sqlQuery, firstName, lastName, email, r.emailIndex(payload.Email), id)
 	if dbErr != nil {
 		return dbErr
 	}
@@ -253,52 +397,81 @@ func (r *repo) UpdateSingleById(ctx context.Context, id int, payload modelUpdate
 	return nil
 }
 
-func (r *repo) DeleteSingleById(ctx context.Context, id int) error {
+// DeleteSingleById deletes the customer if the caller created it, reporting
+// whether it did.
+func (r *repo) DeleteSingleById(ctx context.Context, id int) (bool, error) {
+	ctx, span := tracing.Start(ctx, "customer.repo.DeleteSingleById")
+	defer span.End()
+
 	JWTContext := ctx.Value(auth.JWTContextKey)
 	claim, ok := JWTContext.(*auth.ModelClaim)
 	if !ok {
-		return errors.New("asd")
+		return false, errors.New("asd")
 	}
 
-	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	sqlQuery := "DELETE FROM customer WHERE id = ? AND created_by = ?"
+	result, err := r.conn(ctx).ExecContext(ctx, sqlQuery, id, claim.Email)
 	if err != nil {
-		return fmt.Errorf("could not start a transaction: %w", err)
+		return false, err
 	}
-	defer tx.Rollback()
 
-	sqlQuery := "DELETE FROM customer a WHERE a.id = ? AND a.created_by = ?"
-	_, err = tx.ExecContext(ctx, sqlQuery, id, claim.Email)
+	deleted, err := result.RowsAffected()
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	return deleted > 0, nil
 }
 
+// defaultAddressId is the placeholder address the migrations create first.
+// New customers live there until they set their own.
+const defaultAddressId int = 1
+
 func (r *repo) InsertSingle(ctx context.Context, payload modelCreate) error {
+	ctx, span := tracing.Start(ctx, "customer.repo.InsertSingle")
+	defer span.End()
+
 	loc, err := time.LoadLocation("Asia/Jakarta")
 	if err != nil {
 		return err
 	}
 
+	// created_by is what DeleteSingleById matches the caller against, so
+	// customers inserted without a claim can't be deleted by anyone.
+	var createdBy sql.NullString
+	if claim, ok := ctx.Value(auth.JWTContextKey).(*auth.ModelClaim); ok && claim.Email != "" {
+		createdBy = sql.NullString{String: claim.Email, Valid: true}
+	}
+
 	now := time.Now().In(loc)
 	sqlQuery :=
 		`INSERT INTO customer (
 				first_name,
 				last_name,
 				email,
+				email_bidx,
 				created_at,
+				created_by,
 				address_id
 			)
-		VALUES (?, ?, ?, ?, ?);`
+		VALUES (?, ?, ?, ?, ?, ?, ?);`
+
+	firstName, err := r.fields.Encrypt(columnCustomerFirstName, payload.FirstName)
+	if err != nil {
+		return err
+	}
 
-	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	lastName, err := r.fields.Encrypt(columnCustomerLastName, payload.LastName)
 	if err != nil {
-		return fmt.Errorf("could not begin a transacation: %w", err)
+		return err
 	}
-	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx, sqlQuery, payload.FirstName, payload.LastName, payload.Email, now, 1)
+	email, err := r.fields.Encrypt(columnCustomerEmail, payload.Email)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.conn(ctx).ExecContext(ctx, sqlQuery, firstName, lastName, email, r.emailIndex(&payload.Email), now, createdBy, defaultAddressId)
 	if err != nil {
 		return err
 	}
@@ -307,27 +480,50 @@ func (r *repo) InsertSingle(ctx context.Context, payload modelCreate) error {
 }
 
 func (r *repo) UpdateSingleAddressByCustomerId(ctx context.Context, id uint16, payload address.ModelUpdate) error {
+	ctx, span := tracing.Start(ctx, "customer.repo.UpdateSingleAddressByCustomerId")
+	defer span.End()
+
 	fields := []string{}
 	structFields := []any{}
 
 	if payload.Address != nil {
+		value, err := r.fields.Encrypt(columnAddressAddress, *payload.Address)
+		if err != nil {
+			return err
+		}
+
 		fields = append(fields, "address=?")
-		structFields = append(structFields, payload.Address)
+		structFields = append(structFields, value)
 	}
 
 	if payload.Address2 != nil {
+		value, err := r.fields.Encrypt(columnAddressAddress2, *payload.Address2)
+		if err != nil {
+			return err
+		}
+
 		fields = append(fields, "address2=?")
-		structFields = append(structFields, payload.Address2)
+		structFields = append(structFields, value)
 	}
 
 	if payload.District != nil {
+		value, err := r.fields.Encrypt(columnAddressDistrict, *payload.District)
+		if err != nil {
+			return err
+		}
+
 		fields = append(fields, "district=?")
-		structFields = append(structFields, payload.District)
+		structFields = append(structFields, value)
 	}
 
 	if payload.PostalCode != nil {
+		value, err := r.fields.Encrypt(columnAddressPostalCode, *payload.PostalCode)
+		if err != nil {
+			return err
+		}
+
 		fields = append(fields, "postal_code=?")
-		structFields = append(structFields, payload.PostalCode)
+		structFields = append(structFields, value)
 	}
 
 	fields = append(fields, "last_update=?")
@@ -336,11 +532,415 @@ func (r *repo) UpdateSingleAddressByCustomerId(ctx context.Context, id uint16, p
 	fieldsStr := strings.Join(fields, ", ")
 	structFields = append(structFields, id)
 
-	sqlQuery := fmt.Sprintf("UPDATE address SET %s WHERE address_id=?", fieldsStr)
-	_, err := r.db.ExecContext(ctx, sqlQuery, structFields...)
+	sqlQuery := fmt.Sprintf("UPDATE address SET %s WHERE id=?", fieldsStr)
+	_, err := r.conn(ctx).ExecContext(ctx, sqlQuery, structFields...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repo) UpdateSingleAddressIdById(ctx context.Context, id int, addressId int) error {
+	ctx, span := tracing.Start(ctx, "customer.repo.UpdateSingleAddressIdById")
+	defer span.End()
+
+	const sqlQuery string = "UPDATE customer SET address_id=? WHERE id=?"
+	_, err := r.conn(ctx).ExecContext(ctx, sqlQuery, addressId, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repo) DeactivateSingleById(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "customer.repo.DeactivateSingleById")
+	defer span.End()
+
+	const sqlQuery string = "UPDATE customer SET active=false WHERE id=?"
+	_, err := r.conn(ctx).ExecContext(ctx, sqlQuery, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repo) InsertAudit(ctx context.Context, audit modelAudit) error {
+	ctx, span := tracing.Start(ctx, "customer.repo.InsertAudit")
+	defer span.End()
+
+	const sqlQuery string = `INSERT INTO customer_audit (
+				customer_id,
+				action,
+				actor,
+				detail,
+				created_at
+			)
+		VALUES (?, ?, ?, ?, ?)`
+
+	var detail any
+	if len(audit.Detail) > 0 {
+		detail = string(audit.Detail)
+	}
+
+	_, err := r.conn(ctx).ExecContext(ctx, sqlQuery, audit.CustomerId, audit.Action, audit.Actor, detail, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repo) UpdateAuditCustomerId(ctx context.Context, fromId int, toId int) error {
+	ctx, span := tracing.Start(ctx, "customer.repo.UpdateAuditCustomerId")
+	defer span.End()
+
+	const sqlQuery string = "UPDATE customer_audit SET customer_id=? WHERE customer_id=?"
+	_, err := r.conn(ctx).ExecContext(ctx, sqlQuery, toId, fromId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repo) InsertMerge(ctx context.Context, merge modelMerge) error {
+	ctx, span := tracing.Start(ctx, "customer.repo.InsertMerge")
+	defer span.End()
+
+	const sqlQuery string = `INSERT INTO customer_merge (
+				survivor_id,
+				duplicate_id,
+				duplicate_snapshot,
+				merged_by,
+				merged_at
+			)
+		VALUES (?, ?, ?, ?, ?)`
+
+	snapshot, err := r.fields.Encrypt(columnMergeSnapshot, string(merge.DuplicateSnapshot))
+	if err != nil {
+		return err
+	}
+
+	_, err = r.conn(ctx).ExecContext(ctx, sqlQuery,
+		merge.SurvivorId,
+		merge.DuplicateId,
+		snapshot,
+		merge.MergedBy,
+		merge.MergedAt,
+	)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// SelectSingleByIdUnscoped is SelectSingleById without the active filter, so
+// merged and erased customers can still be looked up.
+func (r *repo) SelectSingleByIdUnscoped(ctx context.Context, id int) (modelSQL, error) {
+	ctx, span := tracing.Start(ctx, "customer.repo.SelectSingleByIdUnscoped")
+	defer span.End()
+
+	var modelSQL modelSQL
+	const sqlQuery string = `
+		SELECT a.id,
+			a.email,
+			a.first_name,
+			a.last_name,
+			a.address_id,
+			a.active,
+			a.created_at,
+			b.id,
+			b.address,
+			b.district,
+			b.city_id,
+			b.postal_code
+		FROM customer a
+			JOIN address b ON b.id = a.address_id
+		WHERE a.id=?`
+	rows, err := r.reader(ctx).QueryContext(ctx, sqlQuery, id)
+	if err != nil {
+		return modelSQL, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		err = rows.Scan(
+			&modelSQL.id,
+			&modelSQL.email,
+			&modelSQL.firstName,
+			&modelSQL.lastName,
+			&modelSQL.addressId,
+			&modelSQL.active,
+			&modelSQL.createdAt,
+			&modelSQL.address.Id,
+			&modelSQL.address.Address,
+			&modelSQL.address.District,
+			&modelSQL.address.CityId,
+			&modelSQL.address.PostalCode,
+		)
+		if err != nil {
+			return modelSQL, err
+		}
+
+		if err = r.decryptModel(&modelSQL); err != nil {
+			return modelSQL, err
+		}
+	}
+
+	return modelSQL, nil
+}
+
+func (r *repo) SelectAuditByCustomerId(ctx context.Context, id int) ([]modelAudit, error) {
+	ctx, span := tracing.Start(ctx, "customer.repo.SelectAuditByCustomerId")
+	defer span.End()
+
+	var audit modelAudit
+	audits := []modelAudit{}
+	const sqlQuery string = `SELECT id,
+			customer_id,
+			action,
+			actor,
+			detail,
+			created_at
+		FROM customer_audit
+		WHERE customer_id = ?
+		ORDER BY id ASC`
+
+	rows, err := r.reader(ctx).QueryContext(ctx, sqlQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var detail sql.NullString
+
+		err = rows.Scan(&audit.Id, &audit.CustomerId, &audit.Action, &audit.Actor, &detail, &audit.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		audit.Detail = nil
+		if detail.Valid {
+			audit.Detail = []byte(detail.String)
+		}
+
+		audits = append(audits, audit)
+	}
+
+	return audits, rows.Err()
+}
+
+func (r *repo) SelectMergesByCustomerId(ctx context.Context, id int) ([]modelMerge, error) {
+	ctx, span := tracing.Start(ctx, "customer.repo.SelectMergesByCustomerId")
+	defer span.End()
+
+	var merge modelMerge
+	merges := []modelMerge{}
+	const sqlQuery string = `SELECT survivor_id,
+			duplicate_id,
+			duplicate_snapshot,
+			merged_by,
+			merged_at
+		FROM customer_merge
+		WHERE survivor_id = ?
+			OR duplicate_id = ?
+		ORDER BY id ASC`
+
+	rows, err := r.reader(ctx).QueryContext(ctx, sqlQuery, id, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var snapshot string
+
+		err = rows.Scan(&merge.SurvivorId, &merge.DuplicateId, &snapshot, &merge.MergedBy, &merge.MergedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot, err = r.fields.Decrypt(columnMergeSnapshot, snapshot)
+		if err != nil {
+			return nil, err
+		}
+
+		merge.DuplicateSnapshot = []byte(snapshot)
+		merges = append(merges, merge)
+	}
+
+	return merges, rows.Err()
+}
+
+func (r *repo) SelectErasureByCustomerId(ctx context.Context, id int) (modelErasure, bool, error) {
+	ctx, span := tracing.Start(ctx, "customer.repo.SelectErasureByCustomerId")
+	defer span.End()
+
+	var erasure modelErasure
+	const sqlQuery string = `SELECT customer_id,
+			reason,
+			erased_by,
+			erased_at
+		FROM customer_erasure
+		WHERE customer_id = ?`
+
+	err := r.reader(ctx).QueryRowContext(ctx, sqlQuery, id).Scan(&erasure.CustomerId, &erasure.Reason, &erasure.ErasedBy, &erasure.ErasedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return erasure, false, nil
+	}
+
+	if err != nil {
+		return erasure, false, err
+	}
+
+	return erasure, true, nil
+}
+
+func (r *repo) CountByAddressId(ctx context.Context, addressId int) (int, error) {
+	ctx, span := tracing.Start(ctx, "customer.repo.CountByAddressId")
+	defer span.End()
+
+	var count int
+	const sqlQuery string = "SELECT COUNT(*) FROM customer WHERE address_id = ?"
+
+	err := r.reader(ctx).QueryRowContext(ctx, sqlQuery, addressId).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// EraseSingleById overwrites the customer's personal data in place and
+// deactivates it. The row itself stays so foreign keys keep pointing at it.
+func (r *repo) EraseSingleById(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "customer.repo.EraseSingleById")
+	defer span.End()
+
+	const sqlQuery string = "UPDATE customer SET email=?, email_bidx=?, first_name=?, last_name=?, active=false WHERE id=?"
+
+	email := erasedEmail(id)
+	_, err := r.conn(ctx).ExecContext(ctx, sqlQuery, email, r.emailIndex(&email), erasedName, "", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repo) EraseAddressById(ctx context.Context, addressId int) error {
+	ctx, span := tracing.Start(ctx, "customer.repo.EraseAddressById")
+	defer span.End()
+
+	const sqlQuery string = "UPDATE address SET address=?, address2=NULL, district=?, postal_code=NULL, last_update=? WHERE id=?"
+	_, err := r.conn(ctx).ExecContext(ctx, sqlQuery, "", "", time.Now(), addressId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repo) EraseMergeSnapshotsByDuplicateId(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "customer.repo.EraseMergeSnapshotsByDuplicateId")
+	defer span.End()
+
+	const sqlQuery string = "UPDATE customer_merge SET duplicate_snapshot=? WHERE duplicate_id=?"
+	_, err := r.conn(ctx).ExecContext(ctx, sqlQuery, `{"erased":true}`, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repo) InsertErasure(ctx context.Context, erasure modelErasure) error {
+	ctx, span := tracing.Start(ctx, "customer.repo.InsertErasure")
+	defer span.End()
+
+	const sqlQuery string = `INSERT INTO customer_erasure (
+				customer_id,
+				reason,
+				erased_by,
+				erased_at
+			)
+		VALUES (?, ?, ?, ?)`
+
+	_, err := r.conn(ctx).ExecContext(ctx, sqlQuery, erasure.CustomerId, erasure.Reason, erasure.ErasedBy, erasure.ErasedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SelectSingleByEmail looks a customer up by email regardless of whether it
+// is active, matching the scope of the unique key. Encrypted emails are
+// matched through their blind index, and rows written before encryption was
+// turned on, which have no blind index until they are rotated, through their
+// plaintext email.
+func (r *repo) SelectSingleByEmail(ctx context.Context, email string) (modelSQL, error) {
+	ctx, span := tracing.Start(ctx, "customer.repo.SelectSingleByEmail")
+	defer span.End()
+
+	var modelSQL modelSQL
+	args := []any{email}
+	sqlQuery := `
+		SELECT a.id,
+			a.email,
+			a.first_name,
+			a.last_name,
+			a.address_id,
+			a.active,
+			a.created_at,
+			b.id,
+			b.address,
+			b.district,
+			b.city_id,
+			b.postal_code
+		FROM customer a
+			JOIN address b ON b.id = a.address_id
+		WHERE a.email=?`
+
+	if r.fields.Enabled(columnCustomerEmail) {
+		sqlQuery = strings.Replace(sqlQuery, "a.email=?", "(a.email_bidx=? OR (a.email_bidx IS NULL AND a.email=?))", 1)
+		args = []any{r.emailIndex(&email), email}
+	}
+
+	rows, err := r.reader(ctx).QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return modelSQL, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		err = rows.Scan(
+			&modelSQL.id,
+			&modelSQL.email,
+			&modelSQL.firstName,
+			&modelSQL.lastName,
+			&modelSQL.addressId,
+			&modelSQL.active,
+			&modelSQL.createdAt,
+			&modelSQL.address.Id,
+			&modelSQL.address.Address,
+			&modelSQL.address.District,
+			&modelSQL.address.CityId,
+			&modelSQL.address.PostalCode,
+		)
+		if err != nil {
+			return modelSQL, err
+		}
+
+		if err = r.decryptModel(&modelSQL); err != nil {
+			return modelSQL, err
+		}
+	}
+
+	return modelSQL, nil
+}
@@ -0,0 +1,75 @@
+package customer
+
+import (
+	"database/sql"
+)
+
+// Columns that can be listed in the encryption config.
+const (
+	columnCustomerEmail     string = "customer.email"
+	columnCustomerFirstName string = "customer.first_name"
+	columnCustomerLastName  string = "customer.last_name"
+	columnAddressAddress    string = "address.address"
+	columnAddressAddress2   string = "address.address2"
+	columnAddressDistrict   string = "address.district"
+	columnAddressPostalCode string = "address.postal_code"
+	columnMergeSnapshot     string = "customer_merge.duplicate_snapshot"
+)
+
+func (r *repo) decryptNullString(column string, value *sql.NullString) error {
+	if !value.Valid {
+		return nil
+	}
+
+	plaintext, err := r.fields.Decrypt(column, value.String)
+	if err != nil {
+		return err
+	}
+
+	value.String = plaintext
+
+	return nil
+}
+
+// decryptModel replaces the encrypted columns of a scanned row with their
+// plaintext.
+func (r *repo) decryptModel(modelSQL *modelSQL) error {
+	columns := []struct {
+		name  string
+		value *sql.NullString
+	}{
+		{columnCustomerEmail, &modelSQL.email},
+		{columnCustomerFirstName, &modelSQL.firstName},
+		{columnCustomerLastName, &modelSQL.lastName},
+		{columnAddressAddress, &modelSQL.address.Address},
+		{columnAddressDistrict, &modelSQL.address.District},
+		{columnAddressPostalCode, &modelSQL.address.PostalCode},
+	}
+
+	for _, column := range columns {
+		if err := r.decryptNullString(column.name, column.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encryptPtr encrypts an optional value, keeping nil as SQL NULL.
+func (r *repo) encryptPtr(column string, value *string) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	return r.fields.Encrypt(column, *value)
+}
+
+// emailIndex is the blind index stored next to an encrypted email, or NULL
+// when emails are kept in plaintext.
+func (r *repo) emailIndex(email *string) any {
+	if email == nil || !r.fields.Enabled(columnCustomerEmail) {
+		return nil
+	}
+
+	return r.fields.BlindIndex(columnCustomerEmail, *email)
+}
@@ -0,0 +1,133 @@
+package customer
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mmiftahrzki/customer/encryption"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFields encrypts the customer columns with a keyring holding keys, the
+// key k<n> being n repeated, and active as its active key. The blind index
+// key is the same for every keyring, as it is across a rotation.
+func testFields(t *testing.T, active string, keys ...string) *encryption.Fields {
+	entries := []string{}
+	for i, id := range keys {
+		entries = append(entries, fmt.Sprintf("%q: %q", id, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{byte(i + 1)}, 32))))
+	}
+
+	indexKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, 32))
+	content := fmt.Sprintf(`{"active": %q, "keys": {%s}, "index_key": %q}`, active, strings.Join(entries, ","), indexKey)
+
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	keyring, err := encryption.LoadKeyring(path)
+	require.NoError(t, err)
+
+	return encryption.New(keyring, []string{columnCustomerEmail, columnCustomerFirstName, columnCustomerLastName})
+}
+
+// storedEmail returns the email column of customer id and its blind index as
+// they are stored.
+func storedEmail(t *testing.T, r repo, id int) (string, sql.NullString) {
+	ctx := context.Background()
+
+	var email string
+	var index sql.NullString
+	require.NoError(t, r.conn(ctx).QueryRowContext(ctx, "SELECT email, email_bidx FROM customer WHERE id = ?", id).Scan(&email, &index))
+
+	return email, index
+}
+
+func TestRepoEncryption(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, plain repo) {
+		ctx := context.Background()
+		r := newRepo(plain.db, testFields(t, "k1", "k1"))
+
+		insertCustomers(t, r, modelCreate{FirstName: "Mary", LastName: "Smith", Email: "mary@example.com"})
+
+		email, index := storedEmail(t, r, 1)
+		assert.True(t, strings.HasPrefix(email, "enc:v1:k1:"), "the email should be stored encrypted")
+		assert.True(t, index.Valid, "the email should be stored with its blind index")
+
+		mary, err := r.SelectSingleById(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "mary@example.com", mary.email.String)
+		assert.Equal(t, "Mary", mary.firstName.String)
+
+		mary, err = r.SelectSingleByEmail(ctx, " Mary@Example.com")
+		require.NoError(t, err)
+		assert.Equal(t, int16(1), mary.id.Int16, "the blind index should match regardless of case and spacing")
+
+		mary, err = plain.SelectSingleByEmail(ctx, "mary@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, modelSQL{}, mary, "the plaintext email is not stored")
+	})
+}
+
+func TestRepoEncryptionPlaintextRows(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, plain repo) {
+		ctx := context.Background()
+
+		// Linda was created before encryption was turned on.
+		insertCustomers(t, plain, modelCreate{FirstName: "Linda", LastName: "Williams", Email: "linda@example.com"})
+
+		r := newRepo(plain.db, testFields(t, "k1", "k1"))
+
+		_, index := storedEmail(t, r, 1)
+		require.False(t, index.Valid)
+
+		linda, err := r.SelectSingleByEmail(ctx, "linda@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, int16(1), linda.id.Int16, "rows without a blind index should be found by their plaintext email")
+
+		svc := newService(r, serviceCache{})
+		err = svc.CreateNewSingle(ctx, modelCreate{FirstName: "Linda", LastName: "Williams", Email: "linda@example.com"})
+		assert.ErrorIs(t, err, errCustomerAlreadyExists)
+	})
+}
+
+func TestRotateKeys(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, plain repo) {
+		ctx := context.Background()
+
+		insertCustomers(t, plain,
+			modelCreate{FirstName: "Mary", LastName: "Smith", Email: "mary@example.com"},
+			modelCreate{FirstName: "Linda", LastName: "Williams", Email: "linda@example.com"},
+			modelCreate{FirstName: "Barbara", LastName: "Jones", Email: "barbara@example.com"},
+		)
+
+		for _, keys := range [][]string{{"k1"}, {"k1", "k2"}} {
+			active := keys[len(keys)-1]
+			fields := testFields(t, active, keys...)
+			r := newRepo(plain.db, fields)
+
+			require.NoError(t, RotateKeys(ctx, plain.db, fields, 2))
+
+			for id, want := range map[int]string{1: "mary@example.com", 2: "linda@example.com", 3: "barbara@example.com"} {
+				email, index := storedEmail(t, r, id)
+				assert.True(t, strings.HasPrefix(email, "enc:v1:"+active+":"), "customer %d should be under %s", id, active)
+				assert.Equal(t, fields.BlindIndex(columnCustomerEmail, want), index.String)
+
+				customer, err := r.SelectSingleByEmail(ctx, want)
+				require.NoError(t, err)
+				assert.Equal(t, int16(id), customer.id.Int16)
+			}
+		}
+
+		r := newRepo(plain.db, testFields(t, "k2", "k1", "k2"))
+		_, rotated, err := r.RotateBatch(ctx, rotationTargets[0], 0, 10)
+		require.NoError(t, err)
+		assert.Zero(t, rotated, "rows under the active key are left alone")
+	})
+}
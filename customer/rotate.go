@@ -0,0 +1,169 @@
+package customer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mmiftahrzki/customer/database"
+	"github.com/mmiftahrzki/customer/encryption"
+	"github.com/mmiftahrzki/customer/logger"
+)
+
+// rotationTarget lists the columns of a table that may hold encrypted
+// values. blindIndexes maps a column to the column holding its blind index.
+type rotationTarget struct {
+	table        string
+	columns      []string
+	blindIndexes map[string]string
+}
+
+var rotationTargets = []rotationTarget{
+	{
+		table:        "customer",
+		columns:      []string{"email", "first_name", "last_name"},
+		blindIndexes: map[string]string{"email": "email_bidx"},
+	},
+	{
+		table:   "address",
+		columns: []string{"address", "address2", "district", "postal_code"},
+	},
+	{
+		table:   "customer_merge",
+		columns: []string{"duplicate_snapshot"},
+	},
+}
+
+// RotateKeys re-encrypts every configured column still stored in plaintext
+// or under a retired key with the keyring's active key. Rows are processed in
+// batches of batchSize, each batch in its own transaction, so a rotation can
+// be interrupted and resumed.
+func RotateKeys(ctx context.Context, db *database.DB, fields *encryption.Fields, batchSize int) error {
+	log := logger.GetLogger().WithField("component", "customerRotateKeys")
+	r := newRepo(db, fields)
+
+	for _, target := range rotationTargets {
+		afterId, total := int64(0), 0
+
+		for {
+			var lastId int64
+			var rotated int
+
+			err := db.Retry(ctx, "customer.rotateKeys", func(ctx context.Context) (err error) {
+				lastId, rotated, err = r.RotateBatch(ctx, target, afterId, batchSize)
+
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("rotating %s after id %d: %w", target.table, afterId, err)
+			}
+
+			if lastId == afterId {
+				break
+			}
+
+			afterId = lastId
+			total += rotated
+		}
+
+		log.Infof("%d %s rows re-encrypted", total, target.table)
+	}
+
+	return nil
+}
+
+// RotateBatch re-encrypts up to size rows of target with an id greater than
+// afterId as one unit of work. It returns the last id it looked at, which
+// equals afterId once the table is exhausted, and how many rows it rewrote.
+func (r *repo) RotateBatch(ctx context.Context, target rotationTarget, afterId int64, size int) (int64, int, error) {
+	lastId, rotated := afterId, 0
+
+	err := r.db.WithTx(ctx, func(ctx context.Context, tx *database.Tx) error {
+		selectQuery := r.db.Dialect.Paginate(fmt.Sprintf("SELECT id, %s FROM %s WHERE id > ? ORDER BY id ASC",
+			strings.Join(target.columns, ", "), target.table), true)
+
+		rows, err := tx.QueryContext(ctx, selectQuery, afterId, size)
+		if err != nil {
+			return err
+		}
+
+		type row struct {
+			id     int64
+			values []sql.NullString
+		}
+		batch := []row{}
+
+		for rows.Next() {
+			current := row{values: make([]sql.NullString, len(target.columns))}
+			dest := []any{&current.id}
+			for i := range current.values {
+				dest = append(dest, &current.values[i])
+			}
+
+			if err = rows.Scan(dest...); err != nil {
+				rows.Close()
+
+				return err
+			}
+
+			batch = append(batch, current)
+		}
+		rows.Close()
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for _, current := range batch {
+			lastId = current.id
+			assignments := []string{}
+			args := []any{}
+
+			for i, name := range target.columns {
+				column := target.table + "." + name
+				value := current.values[i]
+
+				if !value.Valid || !r.fields.NeedsRotation(column, value.String) {
+					continue
+				}
+
+				plaintext, err := r.fields.Decrypt(column, value.String)
+				if err != nil {
+					return fmt.Errorf("%s id %d: %w", column, current.id, err)
+				}
+
+				encrypted, err := r.fields.Encrypt(column, plaintext)
+				if err != nil {
+					return err
+				}
+
+				assignments = append(assignments, name+"=?")
+				args = append(args, encrypted)
+
+				if indexColumn, ok := target.blindIndexes[name]; ok {
+					assignments = append(assignments, indexColumn+"=?")
+					args = append(args, r.fields.BlindIndex(column, plaintext))
+				}
+			}
+
+			if len(assignments) == 0 {
+				continue
+			}
+
+			updateQuery := fmt.Sprintf("UPDATE %s SET %s WHERE id=?", target.table, strings.Join(assignments, ", "))
+			if _, err = tx.ExecContext(ctx, updateQuery, append(args, current.id)...); err != nil {
+				return err
+			}
+
+			rotated++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return afterId, 0, err
+	}
+
+	return lastId, rotated, nil
+}
@@ -2,112 +2,169 @@ package customer
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"reflect"
 	"sort"
+	"time"
 
-	"github.com/go-sql-driver/mysql"
+	"github.com/mmiftahrzki/customer/auth"
 	"github.com/mmiftahrzki/customer/customer/address"
+	"github.com/mmiftahrzki/customer/customer/duplicate"
+	"github.com/mmiftahrzki/customer/database"
 	"github.com/mmiftahrzki/customer/logger"
+	"github.com/mmiftahrzki/customer/tracing"
 	"github.com/sirupsen/logrus"
 )
 
 type service struct {
-	repo repo
-	log  *logrus.Entry
+	repo  repo
+	cache serviceCache
+	log   *logrus.Entry
 }
 
 var errCustomerAlreadyExists = errors.New("customer already exists")
 var errCustomerNotFound = errors.New("customer not found")
 var errInvalidCustomerAddressMismatch = errors.New("customer address mismatch")
 
-func newService(r repo) service {
+func newService(r repo, c serviceCache) service {
 	svc := service{
-		repo: r,
-		log:  logger.GetLogger().WithField("component", "customerService"),
+		repo:  r,
+		cache: c,
+		log:   logger.GetLogger().WithField("component", "customerService"),
 	}
 
 	return svc
 }
 
-func (svc *service) GetMultiple(ctx context.Context) ([]modelRead, error) {
+// withTx runs fn as a unit of work carried by the context handed to fn, so
+// every repository write it makes commits or rolls back together. A unit of
+// work of its own is run again from the start after a deadlock or lock wait
+// timeout; one nested in the unit of work ctx carries runs in a savepoint,
+// and the failure is left to the outermost unit to retry.
+func (svc *service) withTx(ctx context.Context, operation string, fn func(ctx context.Context) error) error {
+	unit := func(ctx context.Context) error {
+		return svc.repo.db.WithTx(ctx, func(ctx context.Context, _ *database.Tx) error {
+			return fn(ctx)
+		})
+	}
+
+	if _, ok := database.TxFromContext(ctx); ok {
+		return unit(ctx)
+	}
+
+	return svc.repo.db.Retry(ctx, operation, unit)
+}
+
+func (svc *service) GetMultiple(ctx context.Context, limit int) ([]modelRead, error) {
+	ctx, span := tracing.Start(ctx, "customer.service.GetMultiple")
+	defer span.End()
+
 	var customers []modelRead
 
 	select {
 	case <-ctx.Done():
-		svc.log.Info("deadline exceeded from service layer")
+		logger.WithContext(ctx, svc.log).Info("deadline exceeded from service layer")
 
 		return customers, ctx.Err()
 	default:
-		customerSqls, repoErr := svc.repo.SelectAll(ctx)
-		if repoErr != nil {
-			return customers, repoErr
-		}
+		return svc.cachedPage(ctx, pageKey("first", 0, limit), func(ctx context.Context) ([]modelRead, error) {
+			customerSqls, repoErr := svc.repo.SelectAll(ctx, limit)
+			if repoErr != nil {
+				return customers, repoErr
+			}
 
-		for _, customerSql := range customerSqls {
-			customer := newReadModel(customerSql)
-			customers = append(customers, customer)
-		}
+			for _, customerSql := range customerSqls {
+				customer := newReadModel(customerSql)
+				customers = append(customers, customer)
+			}
+
+			return customers, nil
+		})
 	}
+}
 
-	return customers, nil
+func (svc *service) Export(ctx context.Context, fn func(modelExport) error) error {
+	ctx, span := tracing.Start(ctx, "customer.service.Export")
+	defer span.End()
+
+	return svc.repo.SelectAllStream(ctx, func(customerSql modelSQL) error {
+		return fn(newExportModel(customerSql))
+	})
 }
 
-func (svc *service) GetMultiplePrev(ctx context.Context, id int) (customers []modelRead, err error) {
-	customer, err := svc.GetSingleById(ctx, id)
-	if err != nil {
-		return
-	}
+func (svc *service) GetMultiplePrev(ctx context.Context, id int, limit int) ([]modelRead, error) {
+	ctx, span := tracing.Start(ctx, "customer.service.GetMultiplePrev")
+	defer span.End()
 
-	if reflect.ValueOf(customer).IsZero() {
-		return nil, errors.New("implement me")
-	}
+	return svc.cachedPage(ctx, pageKey("prev", id, limit), func(ctx context.Context) (customers []modelRead, err error) {
+		customer, err := svc.GetSingleById(ctx, id)
+		if err != nil {
+			return
+		}
 
-	customerSqls, err := svc.repo.SelectAllPrev(ctx, customer)
-	if err != nil {
-		return
-	}
+		if reflect.ValueOf(customer).IsZero() {
+			return nil, errors.New("implement me")
+		}
 
-	for _, customerSql := range customerSqls {
-		customer := newReadModel(customerSql)
-		customers = append(customers, customer)
-	}
+		customerSqls, err := svc.repo.SelectAllPrev(ctx, customer, limit)
+		if err != nil {
+			return
+		}
 
-	sort.SliceStable(customers, func(i, j int) bool {
-		return customers[i].Id < customers[j].Id
-	})
+		for _, customerSql := range customerSqls {
+			customer := newReadModel(customerSql)
+			customers = append(customers, customer)
+		}
 
-	return
-}
+		sort.SliceStable(customers, func(i, j int) bool {
+			return customers[i].Id < customers[j].Id
+		})
 
-func (svc *service) GetMultipleNext(ctx context.Context, id int) (customers []modelRead, err error) {
-	customer, err := svc.GetSingleById(ctx, id)
-	if err != nil {
 		return
-	}
+	})
+}
 
-	if reflect.ValueOf(customer).IsZero() {
-		return nil, errors.New("implement me")
-	}
+func (svc *service) GetMultipleNext(ctx context.Context, id int, limit int) ([]modelRead, error) {
+	ctx, span := tracing.Start(ctx, "customer.service.GetMultipleNext")
+	defer span.End()
 
-	customerSqls, err := svc.repo.SelectAllNext(ctx, customer)
-	if err != nil {
-		return
-	}
+	return svc.cachedPage(ctx, pageKey("next", id, limit), func(ctx context.Context) (customers []modelRead, err error) {
+		customer, err := svc.GetSingleById(ctx, id)
+		if err != nil {
+			return
+		}
 
-	for _, customerSql := range customerSqls {
-		customer := newReadModel(customerSql)
-		customers = append(customers, customer)
-	}
+		if reflect.ValueOf(customer).IsZero() {
+			return nil, errors.New("implement me")
+		}
+
+		customerSqls, err := svc.repo.SelectAllNext(ctx, customer, limit)
+		if err != nil {
+			return
+		}
+
+		for _, customerSql := range customerSqls {
+			customer := newReadModel(customerSql)
+			customers = append(customers, customer)
+		}
 
-	return
+		return
+	})
 }
 
 func (svc *service) GetSingleById(ctx context.Context, id int) (modelRead, error) {
+	ctx, span := tracing.Start(ctx, "customer.service.GetSingleById")
+	defer span.End()
+
 	var customer modelRead
 	var emptyCustomerSql modelSQL
 
-	customerSql, repoErr := svc.repo.SelectSingleById(ctx, id)
+	customerSql, repoErr := readThrough(ctx, svc.cache.customers, customerKey(id), func(ctx context.Context) (modelSQL, error) {
+		return svc.repo.SelectSingleById(ctx, id)
+	}, func(customerSql modelSQL) bool {
+		return customerSql != emptyCustomerSql
+	})
 	if repoErr != nil {
 		return customer, repoErr
 	}
@@ -122,61 +179,549 @@ func (svc *service) GetSingleById(ctx context.Context, id int) (modelRead, error
 }
 
 func (svc *service) CreateNewSingle(ctx context.Context, newCustomer modelCreate) error {
+	ctx, span := tracing.Start(ctx, "customer.service.CreateNewSingle")
+	defer span.End()
+
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		repoErr := svc.repo.InsertSingle(ctx, newCustomer)
-		if repoErr != nil {
-			mysqlErr, ok := repoErr.(*mysql.MySQLError)
-			if ok && mysqlErr.Number == 1062 {
+		err := svc.withTx(ctx, "customer.create", func(ctx context.Context) error {
+			var emptyCustomerSql modelSQL
+
+			existing, repoErr := svc.repo.SelectSingleByEmail(ctx, newCustomer.Email)
+			if repoErr != nil {
+				return repoErr
+			}
+
+			if existing != emptyCustomerSql {
 				return errCustomerAlreadyExists
 			}
 
-			return repoErr
+			repoErr = svc.repo.InsertSingle(ctx, newCustomer)
+			if repoErr != nil {
+				if database.IsDuplicateKey(repoErr) {
+					return errCustomerAlreadyExists
+				}
+
+				return repoErr
+			}
+
+			created, repoErr := svc.repo.SelectSingleByEmail(ctx, newCustomer.Email)
+			if repoErr != nil {
+				return repoErr
+			}
+
+			return svc.repo.InsertAudit(ctx, modelAudit{
+				CustomerId: int(created.id.Int16),
+				Action:     auditActionCreate,
+				Actor:      actorFromContext(ctx),
+			})
+		})
+		if err != nil {
+			return err
 		}
-	}
 
-	return nil
+		svc.cache.invalidate()
+
+		return nil
+	}
 }
 
 func (svc *service) ModifySingleById(ctx context.Context, id int, modifiedCustomer modelUpdate) error {
+	ctx, span := tracing.Start(ctx, "customer.service.ModifySingleById")
+	defer span.End()
+
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		_, err := svc.GetSingleById(ctx, id)
+		err := svc.withTx(ctx, "customer.update", func(ctx context.Context) error {
+			_, err := svc.GetSingleById(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			err = svc.repo.UpdateSingleById(ctx, id, modifiedCustomer)
+			if err != nil {
+				return err
+			}
+
+			return svc.repo.InsertAudit(ctx, modelAudit{
+				CustomerId: id,
+				Action:     auditActionUpdate,
+				Actor:      actorFromContext(ctx),
+			})
+		})
 		if err != nil {
 			return err
 		}
 
-		return svc.repo.UpdateSingleById(ctx, id, modifiedCustomer)
+		svc.cache.invalidate(id)
+
+		return nil
 	}
 }
 
 func (svc *service) DeleteSingleById(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "customer.service.DeleteSingleById")
+	defer span.End()
+
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		return svc.repo.DeleteSingleById(ctx, id)
+		err := svc.withTx(ctx, "customer.delete", func(ctx context.Context) error {
+			deleted, err := svc.repo.DeleteSingleById(ctx, id)
+			if err != nil {
+				return err
+			}
+
+			if !deleted {
+				return errCustomerNotFound
+			}
+
+			return svc.repo.InsertAudit(ctx, modelAudit{
+				CustomerId: id,
+				Action:     auditActionDelete,
+				Actor:      actorFromContext(ctx),
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		svc.cache.invalidate(id)
+
+		return nil
 	}
 }
 
 func (svc *service) ModifySingleAddressById(ctx context.Context, customerId int, addressId uint16, modifiedCustomerAddress address.ModelUpdate) error {
+	ctx, span := tracing.Start(ctx, "customer.service.ModifySingleAddressById")
+	defer span.End()
+
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		customerSql, repoErr := svc.repo.SelectSingleById(ctx, customerId)
-		if repoErr != nil {
-			return repoErr
+		err := svc.withTx(ctx, "customer.updateAddress", func(ctx context.Context) error {
+			customerSql, repoErr := svc.repo.SelectSingleById(ctx, customerId)
+			if repoErr != nil {
+				return repoErr
+			}
+
+			if uint16(customerSql.addressId.Int16) != addressId {
+				return errInvalidCustomerAddressMismatch
+			}
+
+			repoErr = svc.repo.UpdateSingleAddressByCustomerId(ctx, addressId, modifiedCustomerAddress)
+			if repoErr != nil {
+				return repoErr
+			}
+
+			detail, repoErr := json.Marshal(map[string]any{"address_id": addressId})
+			if repoErr != nil {
+				return repoErr
+			}
+
+			return svc.repo.InsertAudit(ctx, modelAudit{
+				CustomerId: customerId,
+				Action:     auditActionUpdateAddress,
+				Actor:      actorFromContext(ctx),
+				Detail:     detail,
+			})
+		})
+		if err != nil {
+			return err
 		}
 
-		if uint16(customerSql.addressId.Int16) != addressId {
-			return errInvalidCustomerAddressMismatch
+		svc.cache.clear()
+
+		return nil
+	}
+}
+
+func (svc *service) applyBatchOperation(ctx context.Context, operation modelBatchOperation) error {
+	switch operation.Op {
+	case batchOpCreate:
+		return svc.CreateNewSingle(ctx, operation.create)
+	case batchOpUpdate:
+		return svc.ModifySingleById(ctx, operation.Id, operation.update)
+	case batchOpDelete:
+		return svc.DeleteSingleById(ctx, operation.Id)
+	default:
+		return errBatchUnknownOp
+	}
+}
+
+// RunBatch applies operations in order and returns one error slot per
+// operation. In atomic mode every operation shares a single transaction and
+// nothing is applied unless all of them succeed; otherwise each operation is
+// committed on its own and failures do not affect the others.
+func (svc *service) RunBatch(ctx context.Context, operations []modelBatchOperation, atomic bool) []error {
+	ctx, span := tracing.Start(ctx, "customer.service.RunBatch")
+	defer span.End()
+
+	errs := make([]error, len(operations))
+
+	for i := range operations {
+		errs[i] = operations[i].parse()
+	}
+
+	if !atomic {
+		for i, operation := range operations {
+			if errs[i] != nil {
+				continue
+			}
+
+			errs[i] = svc.withTx(ctx, "customer.batch", func(ctx context.Context) error {
+				return svc.applyBatchOperation(ctx, operation)
+			})
+			svc.cache.invalidate(operation.Id)
 		}
 
-		return svc.repo.UpdateSingleAddressByCustomerId(ctx, addressId, modifiedCustomerAddress)
+		return errs
+	}
+
+	for i := range errs {
+		if errs[i] != nil {
+			for j := range errs {
+				if errs[j] == nil {
+					errs[j] = errBatchNotApplied
+				}
+			}
+
+			return errs
+		}
 	}
+
+	failed := -1
+	err := svc.withTx(ctx, "customer.batch", func(ctx context.Context) error {
+		if failed != -1 {
+			errs[failed], failed = nil, -1
+		}
+
+		for i, operation := range operations {
+			if err := svc.applyBatchOperation(ctx, operation); err != nil {
+				failed = i
+				errs[i] = err
+
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err == nil {
+		for _, operation := range operations {
+			svc.cache.invalidate(operation.Id)
+		}
+
+		return errs
+	}
+
+	for i := range errs {
+		switch {
+		case failed == -1:
+			errs[i] = err
+		case i < failed:
+			errs[i] = errBatchRolledBack
+		case i > failed:
+			errs[i] = errBatchNotApplied
+		}
+	}
+
+	return errs
+}
+
+// actorFromContext names who is making the request, as carried by the JWT
+// claim the auth middleware stores in the context.
+func actorFromContext(ctx context.Context) string {
+	claim, ok := ctx.Value(auth.JWTContextKey).(*auth.ModelClaim)
+	if !ok || claim.Email == "" {
+		return "anonymous"
+	}
+
+	return claim.Email
+}
+
+// FindDuplicates scores every other active customer against id and returns
+// the most likely duplicates first.
+func (svc *service) FindDuplicates(ctx context.Context, id int) ([]modelDuplicate, error) {
+	ctx, span := tracing.Start(ctx, "customer.service.FindDuplicates")
+	defer span.End()
+
+	var emptyCustomerSql modelSQL
+	duplicates := []modelDuplicate{}
+
+	targetSql, err := svc.repo.SelectSingleById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if targetSql == emptyCustomerSql {
+		return nil, errCustomerNotFound
+	}
+
+	target := newDuplicateCandidate(targetSql)
+
+	err = svc.repo.SelectAllStream(ctx, func(customerSql modelSQL) error {
+		if customerSql.id == targetSql.id {
+			return nil
+		}
+
+		score, reasons := duplicate.Score(target, newDuplicateCandidate(customerSql))
+		if score < duplicate.Threshold {
+			return nil
+		}
+
+		duplicates = append(duplicates, modelDuplicate{
+			Customer: newReadModel(customerSql),
+			Score:    score,
+			Reasons:  reasons,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return duplicates[i].Score > duplicates[j].Score
+	})
+
+	if len(duplicates) > duplicateCandidatesLimit {
+		duplicates = duplicates[:duplicateCandidatesLimit]
+	}
+
+	return duplicates, nil
+}
+
+// Merge folds the duplicate customer into the survivor: blank names on the
+// survivor are filled from the duplicate, audit history is re-pointed to the
+// survivor, the duplicate is deactivated and a merge record keeps a snapshot
+// of what it looked like.
+func (svc *service) Merge(ctx context.Context, payload modelMergeCreate) (modelRead, error) {
+	ctx, span := tracing.Start(ctx, "customer.service.Merge")
+	defer span.End()
+
+	var survivor modelRead
+	var emptyCustomerSql modelSQL
+
+	err := payload.validate()
+	if err != nil {
+		return survivor, err
+	}
+
+	actor := actorFromContext(ctx)
+
+	err = svc.withTx(ctx, "customer.merge", func(ctx context.Context) error {
+		survivorSql, err := svc.repo.SelectSingleById(ctx, payload.SurvivorId)
+		if err != nil {
+			return err
+		}
+
+		duplicateSql, err := svc.repo.SelectSingleById(ctx, payload.DuplicateId)
+		if err != nil {
+			return err
+		}
+
+		if survivorSql == emptyCustomerSql || duplicateSql == emptyCustomerSql {
+			return errCustomerNotFound
+		}
+
+		snapshot, err := json.Marshal(newExportModel(duplicateSql))
+		if err != nil {
+			return err
+		}
+
+		firstName, lastName, email := survivorSql.firstName.String, survivorSql.lastName.String, survivorSql.email.String
+		if firstName == "" {
+			firstName = duplicateSql.firstName.String
+		}
+
+		if lastName == "" {
+			lastName = duplicateSql.lastName.String
+		}
+
+		err = svc.repo.UpdateSingleById(ctx, payload.SurvivorId, modelUpdate{FirstName: &firstName, LastName: &lastName, Email: &email})
+		if err != nil {
+			return err
+		}
+
+		if payload.Address == mergeKeepDuplicateAddress {
+			err = svc.repo.UpdateSingleAddressIdById(ctx, payload.SurvivorId, int(duplicateSql.addressId.Int16))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = svc.repo.DeactivateSingleById(ctx, payload.DuplicateId)
+		if err != nil {
+			return err
+		}
+
+		err = svc.repo.UpdateAuditCustomerId(ctx, payload.DuplicateId, payload.SurvivorId)
+		if err != nil {
+			return err
+		}
+
+		err = svc.repo.InsertMerge(ctx, modelMerge{
+			SurvivorId:        payload.SurvivorId,
+			DuplicateId:       payload.DuplicateId,
+			DuplicateSnapshot: snapshot,
+			MergedBy:          actor,
+			MergedAt:          time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		detail, err := json.Marshal(map[string]any{"duplicate_id": payload.DuplicateId, "address": payload.Address})
+		if err != nil {
+			return err
+		}
+
+		return svc.repo.InsertAudit(ctx, modelAudit{
+			CustomerId: payload.SurvivorId,
+			Action:     auditActionMerge,
+			Actor:      actor,
+			Detail:     detail,
+		})
+	})
+	if err != nil {
+		return survivor, err
+	}
+
+	svc.cache.invalidate(payload.SurvivorId, payload.DuplicateId)
+
+	logger.WithContext(ctx, svc.log).Infof("customer %d merged into %d by %s", payload.DuplicateId, payload.SurvivorId, actor)
+
+	return svc.GetSingleById(ctx, payload.SurvivorId)
+}
+
+// GetPersonalData collects everything held about a customer, including
+// inactive ones, into a single machine readable bundle.
+func (svc *service) GetPersonalData(ctx context.Context, id int) (modelPersonalData, error) {
+	ctx, span := tracing.Start(ctx, "customer.service.GetPersonalData")
+	defer span.End()
+
+	var bundle modelPersonalData
+	var emptyCustomerSql modelSQL
+
+	customerSql, err := svc.repo.SelectSingleByIdUnscoped(ctx, id)
+	if err != nil {
+		return bundle, err
+	}
+
+	if customerSql == emptyCustomerSql {
+		return bundle, errCustomerNotFound
+	}
+
+	audits, err := svc.repo.SelectAuditByCustomerId(ctx, id)
+	if err != nil {
+		return bundle, err
+	}
+
+	merges, err := svc.repo.SelectMergesByCustomerId(ctx, id)
+	if err != nil {
+		return bundle, err
+	}
+
+	bundle.Customer = newExportModel(customerSql)
+	bundle.Address = newPersonalDataAddress(customerSql)
+	bundle.Audit = audits
+	bundle.Merges = merges
+	bundle.GeneratedAt = time.Now()
+
+	return bundle, nil
+}
+
+// Erase irreversibly anonymizes a customer. Names and email are overwritten,
+// the address is blanked unless another customer still lives there, merge
+// snapshots of the customer are dropped and a tombstone records who erased
+// it and why.
+func (svc *service) Erase(ctx context.Context, id int, payload modelErasureCreate) (modelErasure, error) {
+	ctx, span := tracing.Start(ctx, "customer.service.Erase")
+	defer span.End()
+
+	var erasure modelErasure
+	var emptyCustomerSql modelSQL
+
+	err := payload.validate()
+	if err != nil {
+		return erasure, err
+	}
+
+	erasure = modelErasure{
+		CustomerId: id,
+		Reason:     payload.Reason,
+		ErasedBy:   actorFromContext(ctx),
+		ErasedAt:   time.Now(),
+	}
+
+	err = svc.withTx(ctx, "customer.erase", func(ctx context.Context) error {
+		customerSql, err := svc.repo.SelectSingleByIdUnscoped(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if customerSql == emptyCustomerSql {
+			return errCustomerNotFound
+		}
+
+		_, erased, err := svc.repo.SelectErasureByCustomerId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if erased {
+			return errCustomerAlreadyErased
+		}
+
+		err = svc.repo.EraseSingleById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		addressId := int(customerSql.addressId.Int16)
+		sharedBy, err := svc.repo.CountByAddressId(ctx, addressId)
+		if err != nil {
+			return err
+		}
+
+		if sharedBy <= 1 {
+			err = svc.repo.EraseAddressById(ctx, addressId)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = svc.repo.EraseMergeSnapshotsByDuplicateId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = svc.repo.InsertErasure(ctx, erasure)
+		if err != nil {
+			return err
+		}
+
+		return svc.repo.InsertAudit(ctx, modelAudit{
+			CustomerId: id,
+			Action:     auditActionErase,
+			Actor:      erasure.ErasedBy,
+		})
+	})
+	if err != nil {
+		return erasure, err
+	}
+
+	svc.cache.invalidate(id)
+
+	logger.WithContext(ctx, svc.log).Infof("customer %d erased by %s", id, erasure.ErasedBy)
+
+	return erasure, nil
 }
@@ -0,0 +1,338 @@
+package customer
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmiftahrzki/customer/auth"
+	"github.com/mmiftahrzki/customer/config"
+	"github.com/mmiftahrzki/customer/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceUnitOfWork(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r repo) {
+		svc := newService(r, serviceCache{})
+		ctx := context.WithValue(context.Background(), auth.JWTContextKey, &auth.ModelClaim{Email: "admin@example.com"})
+		mary := modelCreate{FirstName: "Mary", LastName: "Smith", Email: "mary@example.com"}
+
+		require.NoError(t, svc.CreateNewSingle(ctx, mary))
+
+		created, err := r.SelectSingleByEmail(ctx, mary.Email)
+		require.NoError(t, err)
+		id := int(created.id.Int16)
+		require.NotZero(t, id)
+
+		audits, err := r.SelectAuditByCustomerId(ctx, id)
+		require.NoError(t, err)
+		require.Len(t, audits, 1)
+		assert.Equal(t, auditActionCreate, audits[0].Action)
+		assert.Equal(t, "admin@example.com", audits[0].Actor)
+
+		errs := svc.RunBatch(ctx, []modelBatchOperation{
+			{Op: batchOpCreate, Data: []byte(`{"first_name":"Linda","last_name":"Williams","email":"linda@example.com"}`)},
+			{Op: batchOpCreate, Data: []byte(`{"first_name":"Mary","last_name":"Smith","email":"mary@example.com"}`)},
+		}, true)
+		assert.ErrorIs(t, errs[0], errBatchRolledBack)
+		assert.ErrorIs(t, errs[1], errCustomerAlreadyExists)
+
+		linda, err := r.SelectSingleByEmail(ctx, "linda@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, modelSQL{}, linda)
+
+		audits, err = r.SelectAuditByCustomerId(ctx, id+1)
+		require.NoError(t, err)
+		assert.Empty(t, audits)
+	})
+}
+
+func TestServiceCacheInvalidation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r repo) {
+		svc := newService(r, newServiceCache(config.CustomerConfig{CacheSize: 10, CacheTTL: time.Minute, CacheLoadTimeout: time.Minute}))
+		ctx := context.WithValue(context.Background(), auth.JWTContextKey, &auth.ModelClaim{Email: "admin@example.com"})
+
+		require.NoError(t, svc.CreateNewSingle(ctx, modelCreate{FirstName: "Mary", LastName: "Smith", Email: "mary@example.com"}))
+
+		page, err := svc.GetMultiple(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		id := page[0].Id
+
+		customer, err := svc.GetSingleById(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "Mary Smith", customer.FullName)
+
+		firstName, lastName, email := "Linda", "Williams", "linda@example.com"
+		require.NoError(t, svc.ModifySingleById(ctx, id, modelUpdate{FirstName: &firstName, LastName: &lastName, Email: &email}))
+
+		customer, err = svc.GetSingleById(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "Linda Williams", customer.FullName)
+
+		page, err = svc.GetMultiple(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		assert.Equal(t, "Linda Williams", page[0].FullName)
+
+		require.NoError(t, svc.CreateNewSingle(ctx, modelCreate{FirstName: "Patricia", LastName: "Johnson", Email: "patricia@example.com"}))
+
+		page, err = svc.GetMultiple(ctx, 10)
+		require.NoError(t, err)
+		assert.Len(t, page, 2)
+	})
+}
+
+func TestServiceCacheReadsPrimary(t *testing.T) {
+	open := func() *database.DB {
+		db, err := database.Open(context.Background(), testConfig(t, database.DriverSQLite, ""))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		require.NoError(t, database.Migrate(context.Background(), db))
+
+		return db
+	}
+
+	primary, replica := open(), newRepo(open(), nil)
+	writer := newRepo(primary, nil)
+	mary := modelCreate{FirstName: "Mary", LastName: "Smith", Email: "mary@example.com"}
+	insertCustomers(t, writer, mary)
+	insertCustomers(t, replica, mary)
+
+	maria := "Maria"
+	require.NoError(t, writer.UpdateSingleById(context.Background(), 1, modelUpdate{FirstName: &maria, LastName: &mary.LastName, Email: &mary.Email}))
+
+	// The replica has not caught up with the rename yet.
+	lagging := newRepo(primary.WithReplicas(replica.db.DB), nil)
+	ctx := database.NewSession(context.Background())
+
+	uncached := newService(lagging, serviceCache{})
+	customer, err := uncached.GetSingleById(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Mary Smith", customer.FullName, "uncached reads should go to the replica")
+
+	svc := newService(lagging, newServiceCache(config.CustomerConfig{CacheSize: 10, CacheTTL: time.Minute, CacheLoadTimeout: time.Minute}))
+	for range 2 {
+		customer, err = svc.GetSingleById(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "Maria Smith", customer.FullName, "the cache should not be filled from a lagging replica")
+	}
+}
+
+func TestServiceDelete(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r repo) {
+		svc := newService(r, serviceCache{})
+		owner := context.WithValue(context.Background(), auth.JWTContextKey, &auth.ModelClaim{Email: "admin@example.com"})
+		other := context.WithValue(context.Background(), auth.JWTContextKey, &auth.ModelClaim{Email: "other@example.com"})
+
+		require.NoError(t, svc.CreateNewSingle(owner, modelCreate{FirstName: "Mary", LastName: "Smith", Email: "mary@example.com"}))
+
+		var createdBy string
+		require.NoError(t, r.conn(owner).QueryRowContext(owner, "SELECT created_by FROM customer WHERE id = ?", 1).Scan(&createdBy))
+		assert.Equal(t, "admin@example.com", createdBy)
+
+		assert.ErrorIs(t, svc.DeleteSingleById(other, 1), errCustomerNotFound, "only the creator can delete")
+		assert.ErrorIs(t, svc.DeleteSingleById(owner, 2), errCustomerNotFound)
+
+		mary, err := r.SelectSingleById(owner, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int16(1), mary.id.Int16)
+
+		audits, err := r.SelectAuditByCustomerId(owner, 1)
+		require.NoError(t, err)
+		require.Len(t, audits, 1, "a delete that did nothing is not audited")
+
+		require.NoError(t, svc.DeleteSingleById(owner, 1))
+
+		mary, err = r.SelectSingleById(owner, 1)
+		require.NoError(t, err)
+		assert.Equal(t, modelSQL{}, mary)
+
+		assert.ErrorIs(t, svc.DeleteSingleById(owner, 1), errCustomerNotFound, "a second delete finds nothing")
+	})
+}
+
+func TestServiceMerge(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r repo) {
+		svc := newService(r, serviceCache{})
+		ctx := context.WithValue(context.Background(), auth.JWTContextKey, &auth.ModelClaim{Email: "admin@example.com"})
+
+		require.NoError(t, svc.CreateNewSingle(ctx, modelCreate{FirstName: "Mary", LastName: "Smith", Email: "mary@example.com"}))
+		require.NoError(t, svc.CreateNewSingle(ctx, modelCreate{FirstName: "Mary", LastName: "Smyth", Email: "m.smyth@example.com"}))
+		require.NoError(t, svc.CreateNewSingle(ctx, modelCreate{FirstName: "Linda", LastName: "Williams", Email: "linda@example.com"}))
+
+		duplicates, err := svc.FindDuplicates(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, duplicates, 1)
+		assert.Equal(t, 2, duplicates[0].Customer.Id)
+		assert.Equal(t, []string{"name"}, duplicates[0].Reasons, "the default address everyone starts at is not a signal")
+
+		_, err = svc.FindDuplicates(ctx, 99)
+		assert.ErrorIs(t, err, errCustomerNotFound)
+
+		survivor, err := svc.Merge(ctx, modelMergeCreate{SurvivorId: 1, DuplicateId: 2, Address: mergeKeepSurvivorAddress})
+		require.NoError(t, err)
+		assert.Equal(t, 1, survivor.Id)
+		assert.Equal(t, "Mary Smith", survivor.FullName)
+
+		duplicate, err := r.SelectSingleByIdUnscoped(ctx, 2)
+		require.NoError(t, err)
+		assert.False(t, duplicate.active.Bool, "the duplicate should be deactivated")
+
+		audits, err := r.SelectAuditByCustomerId(ctx, 1)
+		require.NoError(t, err)
+		actions := []string{}
+		for _, audit := range audits {
+			actions = append(actions, audit.Action)
+		}
+		assert.Equal(t, []string{auditActionCreate, auditActionCreate, auditActionMerge}, actions, "the duplicate's history should move to the survivor")
+		assert.JSONEq(t, `{"duplicate_id":2,"address":"survivor"}`, string(audits[2].Detail))
+
+		audits, err = r.SelectAuditByCustomerId(ctx, 2)
+		require.NoError(t, err)
+		assert.Empty(t, audits)
+
+		merges, err := r.SelectMergesByCustomerId(ctx, 2)
+		require.NoError(t, err)
+		require.Len(t, merges, 1)
+		assert.Equal(t, 1, merges[0].SurvivorId)
+		assert.Equal(t, 2, merges[0].DuplicateId)
+		assert.Equal(t, "admin@example.com", merges[0].MergedBy)
+
+		var snapshot modelExport
+		require.NoError(t, json.Unmarshal(merges[0].DuplicateSnapshot, &snapshot))
+		assert.Equal(t, "m.smyth@example.com", snapshot.Email)
+		assert.Equal(t, "Smyth", snapshot.LastName)
+
+		_, err = svc.Merge(ctx, modelMergeCreate{SurvivorId: 1, DuplicateId: 2})
+		assert.ErrorIs(t, err, errCustomerNotFound, "an inactive duplicate cannot be merged again")
+	})
+}
+
+func TestServiceFindDuplicatesAddress(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r repo) {
+		svc := newService(r, serviceCache{})
+		ctx := context.Background()
+
+		insertCustomers(t, r,
+			modelCreate{FirstName: "Mary", LastName: "Smith", Email: "mary@example.com"},
+			modelCreate{FirstName: "Mary", LastName: "Smyth", Email: "m.smyth@example.com"},
+			modelCreate{FirstName: "Linda", LastName: "Williams", Email: "linda@example.com"},
+		)
+
+		duplicates, err := svc.FindDuplicates(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, duplicates, 1)
+		scoreAtDefault := duplicates[0].Score
+		assert.Equal(t, []string{"name"}, duplicates[0].Reasons)
+
+		insertAddress(t, r, "12 Elm Street", 1, 2, 3)
+
+		duplicates, err = svc.FindDuplicates(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, duplicates, 1, "a shared address alone does not make Linda a duplicate")
+		assert.Equal(t, 2, duplicates[0].Customer.Id)
+		assert.Equal(t, []string{"name", "address"}, duplicates[0].Reasons)
+		assert.Greater(t, duplicates[0].Score, scoreAtDefault)
+	})
+}
+
+// insertAddress adds an address and moves the customers to it.
+func insertAddress(t *testing.T, r repo, address string, customerIds ...int) int {
+	ctx := context.Background()
+
+	_, err := r.conn(ctx).ExecContext(ctx, "INSERT INTO address (address, district, city_id) VALUES (?, ?, ?)", address, "Central", 1)
+	require.NoError(t, err)
+
+	var id int
+	require.NoError(t, r.conn(ctx).QueryRowContext(ctx, "SELECT MAX(id) FROM address").Scan(&id))
+
+	for _, customerId := range customerIds {
+		require.NoError(t, r.UpdateSingleAddressIdById(ctx, customerId, id))
+	}
+
+	return id
+}
+
+func TestServicePersonalData(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r repo) {
+		svc := newService(r, serviceCache{})
+		ctx := context.WithValue(context.Background(), auth.JWTContextKey, &auth.ModelClaim{Email: "admin@example.com", Role: auth.RoleAdmin})
+
+		require.NoError(t, svc.CreateNewSingle(ctx, modelCreate{FirstName: "Mary", LastName: "Smith", Email: "mary@example.com"}))
+		insertAddress(t, r, "12 Elm Street", 1)
+
+		bundle, err := svc.GetPersonalData(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "mary@example.com", bundle.Customer.Email)
+		assert.Equal(t, "12 Elm Street", bundle.Address.Address)
+		require.Len(t, bundle.Audit, 1)
+		assert.Equal(t, auditActionCreate, bundle.Audit[0].Action)
+		assert.Empty(t, bundle.Merges)
+		assert.False(t, bundle.GeneratedAt.IsZero())
+
+		require.NoError(t, r.DeactivateSingleById(ctx, 1))
+
+		bundle, err = svc.GetPersonalData(ctx, 1)
+		require.NoError(t, err, "inactive customers still have personal data")
+		assert.Equal(t, "Mary", bundle.Customer.FirstName)
+
+		_, err = svc.GetPersonalData(ctx, 99)
+		assert.ErrorIs(t, err, errCustomerNotFound)
+	})
+}
+
+func TestServiceErase(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, r repo) {
+		svc := newService(r, serviceCache{})
+		ctx := context.WithValue(context.Background(), auth.JWTContextKey, &auth.ModelClaim{Email: "admin@example.com", Role: auth.RoleAdmin})
+
+		require.NoError(t, svc.CreateNewSingle(ctx, modelCreate{FirstName: "Mary", LastName: "Smith", Email: "mary@example.com"}))
+		require.NoError(t, svc.CreateNewSingle(ctx, modelCreate{FirstName: "Linda", LastName: "Williams", Email: "linda@example.com"}))
+		require.NoError(t, svc.CreateNewSingle(ctx, modelCreate{FirstName: "Patricia", LastName: "Johnson", Email: "patricia@example.com"}))
+		insertAddress(t, r, "12 Elm Street", 1)
+		insertAddress(t, r, "7 Oak Avenue", 2, 3)
+		require.NoError(t, r.InsertMerge(ctx, modelMerge{SurvivorId: 3, DuplicateId: 1, DuplicateSnapshot: []byte(`{"email":"mary@example.com"}`), MergedBy: "admin@example.com", MergedAt: time.Now()}))
+
+		_, err := svc.Erase(ctx, 1, modelErasureCreate{Reason: strings.Repeat("r", 256)})
+		assert.ErrorIs(t, err, errErasureReasonTooLong)
+
+		erasure, err := svc.Erase(ctx, 1, modelErasureCreate{Reason: "customer request"})
+		require.NoError(t, err)
+		assert.Equal(t, "admin@example.com", erasure.ErasedBy)
+
+		bundle, err := svc.GetPersonalData(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, erasedEmail(1), bundle.Customer.Email)
+		assert.Equal(t, erasedName, bundle.Customer.FirstName)
+		assert.Empty(t, bundle.Customer.LastName)
+		assert.Empty(t, bundle.Address.Address, "an address nobody else lives at is erased")
+		assert.Equal(t, auditActionErase, bundle.Audit[len(bundle.Audit)-1].Action)
+
+		merges, err := r.SelectMergesByCustomerId(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, merges, 1)
+		assert.JSONEq(t, `{"erased":true}`, string(merges[0].DuplicateSnapshot))
+
+		stored, erased, err := r.SelectErasureByCustomerId(ctx, 1)
+		require.NoError(t, err)
+		assert.True(t, erased)
+		assert.Equal(t, "customer request", stored.Reason)
+
+		_, err = svc.Erase(ctx, 1, modelErasureCreate{})
+		assert.ErrorIs(t, err, errCustomerAlreadyErased)
+
+		_, err = svc.Erase(ctx, 2, modelErasureCreate{})
+		require.NoError(t, err)
+
+		bundle, err = svc.GetPersonalData(ctx, 3)
+		require.NoError(t, err)
+		assert.Equal(t, "7 Oak Avenue", bundle.Address.Address, "an address shared with another customer is kept")
+
+		_, err = svc.Erase(ctx, 99, modelErasureCreate{})
+		assert.ErrorIs(t, err, errCustomerNotFound)
+	})
+}
@@ -0,0 +1,28 @@
+-- The customer and address tables MySQL deployments already have, which the
+-- MySQL migrations alter rather than create.
+CREATE TABLE address (
+	id SMALLINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	address VARCHAR(50) NOT NULL,
+	address2 VARCHAR(50) NULL,
+	district VARCHAR(20) NOT NULL,
+	city_id SMALLINT UNSIGNED NOT NULL,
+	postal_code VARCHAR(10) NULL,
+	last_update TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	PRIMARY KEY (id)
+);
+
+CREATE TABLE customer (
+	id SMALLINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	first_name VARCHAR(45) NOT NULL,
+	last_name VARCHAR(45) NOT NULL,
+	email VARCHAR(50) NULL,
+	address_id SMALLINT UNSIGNED NOT NULL,
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at DATETIME NOT NULL,
+	created_by VARCHAR(100) NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY uq_customer_email (email),
+	CONSTRAINT fk_customer_address FOREIGN KEY (address_id) REFERENCES address (id)
+);
+
+INSERT INTO address (address, district, city_id) VALUES ('', '', 0);
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type contextKey int
+
+const (
+	sessionContextKey contextKey = iota
+	primaryContextKey
+	dialectContextKey
+	txContextKey
+)
+
+// NewSession returns a copy of ctx that remembers writes made with it, so
+// the reads that follow them in the same request see their own writes
+// rather than a replica that may not have caught up yet.
+func NewSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionContextKey, &atomic.Bool{})
+}
+
+// MarkWritten records a write on the session of ctx, sending its later
+// reads to the primary. It does nothing without a session.
+func MarkWritten(ctx context.Context) {
+	if written, ok := ctx.Value(sessionContextKey).(*atomic.Bool); ok {
+		written.Store(true)
+	}
+}
+
+// WithPrimary returns a copy of ctx whose reads go to the primary.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey, true)
+}
+
+func readsPrimary(ctx context.Context) bool {
+	if primary, _ := ctx.Value(primaryContextKey).(bool); primary {
+		return true
+	}
+
+	written, ok := ctx.Value(sessionContextKey).(*atomic.Bool)
+
+	return ok && written.Load()
+}
+
+// withSystem tells the hooks which dialect a statement runs on.
+func withSystem(ctx context.Context, dialect Dialect) context.Context {
+	return context.WithValue(ctx, dialectContextKey, dialect)
+}
+
+// systemFromContext returns the name, as OpenTelemetry spells it, of the
+// database the statement a hook observes runs on.
+func systemFromContext(ctx context.Context) string {
+	dialect, ok := ctx.Value(dialectContextKey).(Dialect)
+	if !ok {
+		return ""
+	}
+
+	if dialect.Name() == DriverPostgres {
+		return "postgresql"
+	}
+
+	return dialect.Name()
+}
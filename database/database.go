@@ -1,9 +1,14 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net"
+	"strconv"
 	"sync"
+	"time"
 
 	asd "github.com/go-sql-driver/mysql"
 	"github.com/mmiftahrzki/customer/config"
@@ -11,43 +16,139 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+const maxConnectBackoff time.Duration = 30 * time.Second
+
 var once sync.Once
-var db *sql.DB
+var db *DB
 var log *logrus.Entry = logger.GetLogger().WithField("component", "database")
 
-func new(cfg config.DatabaseConfig) (*sql.DB, error) {
+// ping waits for the database to answer, retrying with a doubling backoff
+// so the service can start alongside a database that is still booting.
+func ping(ctx context.Context, db *sql.DB, cfg config.DatabaseConfig, addr string) error {
 	var err error
-	var db *sql.DB
-	var dsn string = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
-		cfg.User,
-		cfg.Password,
-		cfg.Host,
-		cfg.Port,
-		cfg.Name,
-	)
+
+	backoff := cfg.ConnectBackoff
+	attempts := max(cfg.ConnectAttempts, 1)
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if attempt == attempts {
+			break
+		}
+
+		log.Warnf("connection attempt %d of %d to %s failed, retrying in %s: %v", attempt, attempts, addr, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff = nextConnectBackoff(backoff)
+	}
+
+	return err
+}
+
+// nextConnectBackoff doubles backoff up to maxConnectBackoff.
+func nextConnectBackoff(backoff time.Duration) time.Duration {
+	return min(backoff*2, maxConnectBackoff)
+}
+
+// openPool opens a pool of hooked connections to addr, sized as cfg asks.
+func openPool(dialect Dialect, cfg config.DatabaseConfig, addr string) (*sql.DB, error) {
+	connector, err := dialect.connector(cfg, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	db := sql.OpenDB(hookedConnector{Connector: connector, dialect: dialect})
+
+	maxIdle := cfg.MaxIdleConnection
+	if maxIdle == 0 {
+		maxIdle = cfg.MaxConnection / 2
+	}
+
+	db.SetMaxOpenConns(cfg.MaxConnection)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	return db, nil
+}
+
+// port is the configured port, or the dialect's usual one.
+func port(dialect Dialect, cfg config.DatabaseConfig) uint16 {
+	if cfg.Port == 0 {
+		return dialect.defaultPort()
+	}
+
+	return cfg.Port
+}
+
+func splitAddr(addr string) (string, string, error) {
+	return net.SplitHostPort(addr)
+}
+
+// Open connects to the primary, waiting for it as cfg allows, and opens the
+// replicas. Each call opens new pools; the service shares one through New.
+func Open(ctx context.Context, cfg config.DatabaseConfig) (*DB, error) {
+	dialect, err := dialectFor(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
 
 	err = asd.SetLogger(log)
 	if err != nil {
 		return nil, fmt.Errorf("failed to set Logger: %w", err)
 	}
 
-	db, err = sql.Open("mysql", dsn)
+	addr := cfg.Name
+	if dialect.Name() != DriverSQLite {
+		addr = net.JoinHostPort(cfg.Host, strconv.Itoa(int(port(dialect, cfg))))
+	}
+
+	primary, err := openPool(dialect, cfg, addr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open a connection to %s: %w", cfg.Host, err)
+		return nil, fmt.Errorf("invalid connection settings for %s: %w", addr, err)
 	}
 
-	db.SetMaxOpenConns(cfg.MaxConnection)
-	db.SetMaxIdleConns(cfg.MaxConnection / 2)
+	err = ping(ctx, primary, cfg, addr)
+	if err != nil {
+		primary.Close()
+
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
 
-	err = db.Ping()
+	registerMetrics(primary)
+	SetSlowQueryThreshold(cfg.SlowQueryThreshold)
+
+	replicas, err := openReplicas(dialect, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to %s: %w", cfg.Host, err)
+		primary.Close()
+
+		return nil, err
+	}
+
+	db := &DB{
+		DB:       primary,
+		Dialect:  dialect,
+		retry:    retryPolicy{attempts: cfg.TxRetryAttempts, backoff: cfg.TxRetryBackoff, maxBackoff: cfg.TxRetryMaxBackoff},
+		replicas: replicas,
+	}
+	if len(replicas) > 0 {
+		db.watchReplicas(cfg)
 	}
 
 	return db, nil
 }
 
-func New(cfg config.DatabaseConfig) (*sql.DB, error) {
+// New opens the database once; later calls return the same DB.
+func New(ctx context.Context, cfg config.DatabaseConfig) (*DB, error) {
 	var err error
 
 	if db != nil {
@@ -55,7 +156,7 @@ func New(cfg config.DatabaseConfig) (*sql.DB, error) {
 	}
 
 	once.Do(func() {
-		db, err = new(cfg)
+		db, err = Open(ctx, cfg)
 	})
 
 	return db, err
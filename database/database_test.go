@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mmiftahrzki/customer/config"
+	"github.com/stretchr/testify/assert"
+)
+
+var errRefused = errors.New("connection refused")
+
+// flakyConnector refuses the first failures connections it is asked for.
+type flakyConnector struct {
+	failures int32
+	attempts atomic.Int32
+}
+
+func (c *flakyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.attempts.Add(1) <= c.failures {
+		return nil, errRefused
+	}
+
+	return fakeConn{}, nil
+}
+
+func (c *flakyConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestPingRetries(t *testing.T) {
+	connector := &flakyConnector{failures: 2}
+	cfg := config.DatabaseConfig{ConnectAttempts: 3, ConnectBackoff: time.Millisecond}
+
+	assert.NoError(t, ping(context.Background(), sql.OpenDB(connector), cfg, "db:3306"))
+	assert.Equal(t, int32(3), connector.attempts.Load())
+}
+
+func TestPingGivesUp(t *testing.T) {
+	connector := &flakyConnector{failures: 10}
+	cfg := config.DatabaseConfig{ConnectAttempts: 3, ConnectBackoff: time.Millisecond}
+
+	assert.ErrorIs(t, ping(context.Background(), sql.OpenDB(connector), cfg, "db:3306"), errRefused)
+	assert.Equal(t, int32(3), connector.attempts.Load())
+
+	connector = &flakyConnector{failures: 10}
+	cfg.ConnectAttempts = 0
+
+	assert.ErrorIs(t, ping(context.Background(), sql.OpenDB(connector), cfg, "db:3306"), errRefused)
+	assert.Equal(t, int32(1), connector.attempts.Load(), "at least one attempt is made")
+}
+
+func TestPingStopsWhenCancelled(t *testing.T) {
+	connector := &flakyConnector{failures: 10}
+	cfg := config.DatabaseConfig{ConnectAttempts: 5, ConnectBackoff: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := ping(ctx, sql.OpenDB(connector), cfg, "db:3306")
+	assert.ErrorIs(t, err, errRefused)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(1), connector.attempts.Load())
+}
+
+func TestNextConnectBackoff(t *testing.T) {
+	assert.Equal(t, 2*time.Second, nextConnectBackoff(time.Second))
+	assert.Equal(t, 16*time.Second, nextConnectBackoff(8*time.Second))
+	assert.Equal(t, maxConnectBackoff, nextConnectBackoff(20*time.Second))
+	assert.Equal(t, maxConnectBackoff, nextConnectBackoff(maxConnectBackoff))
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mmiftahrzki/customer/config"
+)
+
+const (
+	DriverMySQL    string = "mysql"
+	DriverPostgres string = "postgres"
+	DriverSQLite   string = "sqlite"
+)
+
+// Dialect hides what differs between the supported databases. Queries are
+// written with ? placeholders and the LIMIT clause the three share; the
+// connection rewrites placeholders for the dialect, so only statements
+// built at runtime need the methods below.
+type Dialect interface {
+	// Name is the driver name, as set in DatabaseConfig.Driver.
+	Name() string
+	// Placeholder is the bind parameter for the nth argument, from 1.
+	Placeholder(n int) string
+	// Paginate limits query to the number of rows given by one more
+	// argument and, if lock is set, locks them until the transaction ends
+	// where the database supports row locks.
+	Paginate(query string, lock bool) string
+	// IsDuplicateKey reports whether err is the dialect's unique key
+	// violation.
+	IsDuplicateKey(err error) bool
+
+	// retryReason names the transient failure err is, such as a deadlock,
+	// after which the transaction may succeed when run again, or is empty.
+	retryReason(err error) string
+	connector(cfg config.DatabaseConfig, addr string) (driver.Connector, error)
+	defaultPort() uint16
+	replicationLag(ctx context.Context, db *sql.DB) (time.Duration, error)
+}
+
+var dialects = map[string]Dialect{
+	DriverMySQL:    mysqlDialect{},
+	DriverPostgres: postgresDialect{},
+	DriverSQLite:   sqliteDialect{},
+}
+
+func dialectFor(name string) (Dialect, error) {
+	dialect, ok := dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown database driver %q", name)
+	}
+
+	return dialect, nil
+}
+
+// IsDuplicateKey reports whether err is a unique key violation in any of the
+// supported databases.
+func IsDuplicateKey(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	for _, dialect := range dialects {
+		if dialect.IsDuplicateKey(err) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsRetryable reports whether err is a deadlock, lock wait timeout or
+// serialization failure in any of the supported databases, after which the
+// whole transaction may succeed when run again.
+func IsRetryable(err error) bool {
+	return retryReason(err) != ""
+}
+
+func retryReason(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	for _, dialect := range dialects {
+		if reason := dialect.retryReason(err); reason != "" {
+			return reason
+		}
+	}
+
+	return ""
+}
+
+// rebind replaces the ? placeholders of query with the dialect's own,
+// leaving those inside quotes and comments alone.
+func rebind(dialect Dialect, query string) string {
+	if !strings.Contains(query, "?") || dialect.Placeholder(1) == "?" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				b.WriteString(query[i:])
+
+				return b.String()
+			}
+
+			b.WriteString(query[i : i+end+2])
+			i += end + 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				b.WriteString(query[i:])
+
+				return b.String()
+			}
+
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				b.WriteString(query[i:])
+
+				return b.String()
+			}
+
+			b.WriteString(query[i : i+end+4])
+			i += end + 3
+		case c == '?':
+			n++
+			b.WriteString(dialect.Placeholder(n))
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// dsnConnector opens connections through a driver that only takes a DSN.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
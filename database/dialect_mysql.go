@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	asd "github.com/go-sql-driver/mysql"
+	"github.com/mmiftahrzki/customer/config"
+)
+
+// tlsConfigName is the name the custom TLS config is registered under with
+// the driver.
+const tlsConfigName string = "customer"
+
+// MySQL error numbers the repository tells apart.
+const (
+	erDupEntry        uint16 = 1062
+	erLockWaitTimeout uint16 = 1205
+	erLockDeadlock    uint16 = 1213
+)
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return DriverMySQL
+}
+
+func (mysqlDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (mysqlDialect) Paginate(query string, lock bool) string {
+	query += " LIMIT ?"
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	return query
+}
+
+func (mysqlDialect) IsDuplicateKey(err error) bool {
+	var mysqlErr *asd.MySQLError
+
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == erDupEntry
+}
+
+func (mysqlDialect) retryReason(err error) string {
+	var mysqlErr *asd.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return ""
+	}
+
+	switch mysqlErr.Number {
+	case erLockDeadlock:
+		return retryDeadlock
+	case erLockWaitTimeout:
+		return retryLockTimeout
+	default:
+		return ""
+	}
+}
+
+func (mysqlDialect) defaultPort() uint16 {
+	return 3306
+}
+
+func (mysqlDialect) connector(cfg config.DatabaseConfig, addr string) (driver.Connector, error) {
+	mysqlConfig, err := newDriverConfig(cfg, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return asd.NewConnector(mysqlConfig)
+}
+
+// newTLSConfig builds the TLS config for a CA and client certificate, or
+// returns nil when none is configured and the driver's TLS modes suffice.
+func newTLSConfig(cfg config.DatabaseConfig) (*tls.Config, error) {
+	if cfg.TLSCAFile == "" && cfg.TLSCertFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.TLSServerName,
+		InsecureSkipVerify: cfg.TLSMode == "skip-verify",
+	}
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", cfg.TLSCAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSCertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// newDriverConfig translates cfg into the driver's connection settings for
+// the server at addr.
+func newDriverConfig(cfg config.DatabaseConfig, addr string) (*asd.Config, error) {
+	mysqlConfig := asd.NewConfig()
+	mysqlConfig.User = cfg.User
+	mysqlConfig.Passwd = cfg.Password
+	mysqlConfig.Net = "tcp"
+	mysqlConfig.Addr = addr
+	mysqlConfig.DBName = cfg.Name
+	mysqlConfig.ParseTime = true
+	mysqlConfig.Timeout = cfg.ConnectTimeout
+	mysqlConfig.ReadTimeout = cfg.ReadTimeout
+	mysqlConfig.WriteTimeout = cfg.WriteTimeout
+	mysqlConfig.Params = map[string]string{}
+
+	if cfg.Loc != "" {
+		loc, err := time.LoadLocation(cfg.Loc)
+		if err != nil {
+			return nil, err
+		}
+
+		mysqlConfig.Loc = loc
+	}
+
+	if cfg.Collation != "" {
+		mysqlConfig.Collation = cfg.Collation
+	}
+
+	if cfg.Charset != "" {
+		mysqlConfig.Params["charset"] = cfg.Charset
+	}
+
+	for key, value := range cfg.Params {
+		mysqlConfig.Params[key] = value
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the TLS files: %w", err)
+	}
+
+	switch {
+	case tlsConfig != nil:
+		if err = asd.RegisterTLSConfig(tlsConfigName, tlsConfig); err != nil {
+			return nil, err
+		}
+
+		mysqlConfig.TLSConfig = tlsConfigName
+	case cfg.TLSMode != "":
+		mysqlConfig.TLSConfig = cfg.TLSMode
+	}
+
+	// FormatDSN and ParseDSN round trip the config through the driver's own
+	// validation, which resolves the TLS mode.
+	return asd.ParseDSN(mysqlConfig.FormatDSN())
+}
+
+// replicationLag reads how far the replica is behind its source. MySQL
+// 8.0.22 renamed the statement and its columns, so the older names are tried
+// second.
+func (mysqlDialect) replicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	statements := []struct {
+		query  string
+		column string
+	}{
+		{"SHOW REPLICA STATUS", "Seconds_Behind_Source"},
+		{"SHOW SLAVE STATUS", "Seconds_Behind_Master"},
+	}
+
+	var err error
+
+	for _, statement := range statements {
+		var seconds sql.NullInt64
+
+		seconds, err = queryColumn(ctx, db, statement.query, statement.column)
+		if err != nil {
+			continue
+		}
+
+		if !seconds.Valid {
+			return 0, errNotReplicating
+		}
+
+		return time.Duration(seconds.Int64) * time.Second, nil
+	}
+
+	return 0, err
+}
+
+// queryColumn reads column from the single row a SHOW statement returns.
+func queryColumn(ctx context.Context, db *sql.DB, query string, column string) (sql.NullInt64, error) {
+	var value sql.NullInt64
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return value, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return value, err
+	}
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return value, err
+		}
+
+		return value, errNotReplicating
+	}
+
+	dest := make([]any, len(columns))
+	found := false
+	for i, name := range columns {
+		if name == column {
+			dest[i] = &value
+			found = true
+		} else {
+			dest[i] = &sql.RawBytes{}
+		}
+	}
+
+	if !found {
+		return value, fmt.Errorf("%s returned no %s column", query, column)
+	}
+
+	return value, rows.Scan(dest...)
+}
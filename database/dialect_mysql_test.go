@@ -0,0 +1,151 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mmiftahrzki/customer/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes a self-signed certificate and its key as PEM files
+// in dir and returns their paths.
+func writeCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "db.internal"},
+		DNSNames:              []string{"db.internal"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+
+	return certFile, keyFile
+}
+
+func TestNewDriverConfig(t *testing.T) {
+	cfg := config.DatabaseConfig{
+		User:           "customer",
+		Password:       "p@ss:word/",
+		Name:           "sakila",
+		ConnectTimeout: 5 * time.Second,
+		ReadTimeout:    30 * time.Second,
+		WriteTimeout:   10 * time.Second,
+		Loc:            "Asia/Jakarta",
+		Charset:        "utf8mb4",
+		Collation:      "utf8mb4_unicode_ci",
+		TLSMode:        "false",
+		Params:         map[string]string{"sql_mode": "'ANSI_QUOTES'"},
+	}
+
+	mysqlConfig, err := newDriverConfig(cfg, "db.internal:3307")
+	require.NoError(t, err)
+
+	assert.Equal(t, "customer", mysqlConfig.User)
+	assert.Equal(t, "p@ss:word/", mysqlConfig.Passwd)
+	assert.Equal(t, "tcp", mysqlConfig.Net)
+	assert.Equal(t, "db.internal:3307", mysqlConfig.Addr)
+	assert.Equal(t, "sakila", mysqlConfig.DBName)
+	assert.True(t, mysqlConfig.ParseTime)
+	assert.Equal(t, 5*time.Second, mysqlConfig.Timeout)
+	assert.Equal(t, 30*time.Second, mysqlConfig.ReadTimeout)
+	assert.Equal(t, 10*time.Second, mysqlConfig.WriteTimeout)
+	assert.Equal(t, "Asia/Jakarta", mysqlConfig.Loc.String())
+	assert.Equal(t, "utf8mb4_unicode_ci", mysqlConfig.Collation)
+	assert.Equal(t, map[string]string{"charset": "utf8mb4", "sql_mode": "'ANSI_QUOTES'"}, mysqlConfig.Params)
+	assert.Nil(t, mysqlConfig.TLS)
+
+	cfg.Loc = "Nowhere/Special"
+	_, err = newDriverConfig(cfg, "db.internal:3307")
+	assert.Error(t, err)
+}
+
+func TestNewDriverConfigTLSModes(t *testing.T) {
+	for mode, insecure := range map[string]bool{"true": false, "skip-verify": true, "preferred": true} {
+		mysqlConfig, err := newDriverConfig(config.DatabaseConfig{TLSMode: mode}, "db.internal:3306")
+		require.NoError(t, err, mode)
+		require.NotNil(t, mysqlConfig.TLS, mode)
+		assert.Equal(t, insecure, mysqlConfig.TLS.InsecureSkipVerify, mode)
+		assert.Equal(t, mode == "preferred", mysqlConfig.AllowFallbackToPlaintext, mode)
+	}
+
+	_, err := newDriverConfig(config.DatabaseConfig{TLSMode: "sometimes"}, "db.internal:3306")
+	assert.Error(t, err)
+}
+
+func TestNewDriverConfigTLSFiles(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, t.TempDir())
+	cfg := config.DatabaseConfig{
+		TLSMode:       "true",
+		TLSCAFile:     certFile,
+		TLSCertFile:   certFile,
+		TLSKeyFile:    keyFile,
+		TLSServerName: "db.internal",
+	}
+
+	mysqlConfig, err := newDriverConfig(cfg, "10.0.0.5:3306")
+	require.NoError(t, err)
+	assert.Equal(t, tlsConfigName, mysqlConfig.TLSConfig)
+	require.NotNil(t, mysqlConfig.TLS)
+	assert.Equal(t, "db.internal", mysqlConfig.TLS.ServerName)
+	assert.False(t, mysqlConfig.TLS.InsecureSkipVerify)
+	assert.NotNil(t, mysqlConfig.TLS.RootCAs)
+	assert.Len(t, mysqlConfig.TLS.Certificates, 1)
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir)
+
+	tlsConfig, err := newTLSConfig(config.DatabaseConfig{TLSMode: "true"})
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig, "the driver's modes suffice without files")
+
+	tlsConfig, err = newTLSConfig(config.DatabaseConfig{TLSMode: "skip-verify", TLSCAFile: certFile})
+	require.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.Empty(t, tlsConfig.Certificates)
+
+	tlsConfig, err = newTLSConfig(config.DatabaseConfig{TLSMode: "true", TLSCertFile: certFile, TLSKeyFile: keyFile})
+	require.NoError(t, err)
+	assert.Nil(t, tlsConfig.RootCAs, "the system roots are used without a CA file")
+	assert.Len(t, tlsConfig.Certificates, 1)
+
+	_, err = newTLSConfig(config.DatabaseConfig{TLSCAFile: filepath.Join(dir, "missing.pem")})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = newTLSConfig(config.DatabaseConfig{TLSCAFile: keyFile})
+	assert.ErrorContains(t, err, "no certificate found")
+
+	_, err = newTLSConfig(config.DatabaseConfig{TLSCertFile: certFile, TLSKeyFile: certFile})
+	assert.Error(t, err, "a certificate is not a key")
+
+	_, err = newDriverConfig(config.DatabaseConfig{TLSMode: "true", TLSCAFile: keyFile}, "db.internal:3306")
+	assert.ErrorContains(t, err, "failed to load the TLS files")
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mmiftahrzki/customer/config"
+)
+
+// PostgreSQL error codes the repository tells apart.
+const (
+	uniqueViolation      pq.ErrorCode = "23505"
+	serializationFailure pq.ErrorCode = "40001"
+	deadlockDetected     pq.ErrorCode = "40P01"
+	lockNotAvailable     pq.ErrorCode = "55P03"
+)
+
+// sslModes maps TLSMode onto the sslmode PostgreSQL understands. There is no
+// equivalent of preferred, which validation rejects.
+var sslModes = map[string]string{
+	"":            "disable",
+	"false":       "disable",
+	"true":        "verify-full",
+	"skip-verify": "require",
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return DriverPostgres
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDialect) Paginate(query string, lock bool) string {
+	query += " LIMIT ?"
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	return query
+}
+
+func (postgresDialect) IsDuplicateKey(err error) bool {
+	var pqErr *pq.Error
+
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+func (postgresDialect) retryReason(err error) string {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return ""
+	}
+
+	switch pqErr.Code {
+	case deadlockDetected:
+		return retryDeadlock
+	case lockNotAvailable:
+		return retryLockTimeout
+	case serializationFailure:
+		return retrySerialization
+	default:
+		return ""
+	}
+}
+
+func (postgresDialect) defaultPort() uint16 {
+	return 5432
+}
+
+// quoteDSNValue quotes a value for a key=value connection string.
+func quoteDSNValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+
+	return "'" + value + "'"
+}
+
+func (postgresDialect) connector(cfg config.DatabaseConfig, addr string) (driver.Connector, error) {
+	host, port, err := splitAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		"host":     host,
+		"port":     port,
+		"user":     cfg.User,
+		"password": cfg.Password,
+		"dbname":   cfg.Name,
+		"sslmode":  sslModes[cfg.TLSMode],
+	}
+
+	if cfg.TLSCAFile != "" {
+		params["sslrootcert"] = cfg.TLSCAFile
+	}
+
+	if cfg.TLSCertFile != "" {
+		params["sslcert"] = cfg.TLSCertFile
+		params["sslkey"] = cfg.TLSKeyFile
+	}
+
+	if cfg.ConnectTimeout > 0 {
+		params["connect_timeout"] = strconv.Itoa(int(cfg.ConnectTimeout.Round(time.Second).Seconds()))
+	}
+
+	if cfg.Charset != "" {
+		params["client_encoding"] = cfg.Charset
+	}
+
+	if cfg.Loc != "" {
+		params["timezone"] = cfg.Loc
+	}
+
+	for key, value := range cfg.Params {
+		params[key] = value
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + quoteDSNValue(params[key])
+	}
+
+	return pq.NewConnector(strings.Join(pairs, " "))
+}
+
+// replicationLag reads how long ago the standby replayed the last
+// transaction it received. A standby that has replayed everything it
+// received is up to date however long the primary has been idle.
+func (postgresDialect) replicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	const sqlQuery string = `SELECT CASE
+			WHEN NOT pg_is_in_recovery() THEN NULL
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+		END`
+
+	var seconds sql.NullFloat64
+
+	err := db.QueryRowContext(ctx, sqlQuery).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+
+	if !seconds.Valid {
+		return 0, errNotReplicating
+	}
+
+	return time.Duration(seconds.Float64 * float64(time.Second)), nil
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/mmiftahrzki/customer/config"
+)
+
+// memoryName is the database name SQLite keeps in memory. The pool shares
+// one such database rather than opening a fresh one per connection.
+const memoryName string = ":memory:"
+
+var errReplicasUnsupported = errors.New("sqlite has no replicas")
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return DriverSQLite
+}
+
+func (sqliteDialect) Placeholder(n int) string {
+	return "?"
+}
+
+// Paginate ignores lock: SQLite has no row locks, and the transactions it
+// opens take the write lock on the whole database up front.
+func (sqliteDialect) Paginate(query string, lock bool) string {
+	return query + " LIMIT ?"
+}
+
+func (sqliteDialect) IsDuplicateKey(err error) bool {
+	var sqliteErr sqlite3.Error
+
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+// retryReason treats a database still locked after the busy timeout like a
+// lock wait timeout elsewhere.
+func (sqliteDialect) retryReason(err error) string {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return ""
+	}
+
+	switch sqliteErr.Code {
+	case sqlite3.ErrBusy, sqlite3.ErrLocked:
+		return retryLockTimeout
+	default:
+		return ""
+	}
+}
+
+func (sqliteDialect) defaultPort() uint16 {
+	return 0
+}
+
+// connector opens the database file named by cfg.Name. Transactions are
+// begun IMMEDIATE so concurrent writers wait for the lock, up to
+// cfg.WriteTimeout, instead of failing when they upgrade to it.
+func (sqliteDialect) connector(cfg config.DatabaseConfig, addr string) (driver.Connector, error) {
+	params := url.Values{}
+	params.Set("_txlock", "immediate")
+	params.Set("_foreign_keys", "on")
+
+	if cfg.WriteTimeout > 0 {
+		params.Set("_busy_timeout", strconv.FormatInt(cfg.WriteTimeout.Milliseconds(), 10))
+	}
+
+	if cfg.Loc != "" {
+		params.Set("_loc", cfg.Loc)
+	}
+
+	for key, value := range cfg.Params {
+		params.Set(key, value)
+	}
+
+	name := strings.NewReplacer("%", "%25", "?", "%3F", "#", "%23").Replace(cfg.Name)
+	if cfg.Name == memoryName {
+		params.Set("cache", "shared")
+	}
+
+	return dsnConnector{dsn: "file:" + name + "?" + params.Encode(), driver: &sqlite3.SQLiteDriver{}}, nil
+}
+
+func (sqliteDialect) replicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	return 0, errReplicasUnsupported
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mmiftahrzki/customer/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebind(t *testing.T) {
+	postgres := postgresDialect{}
+
+	assert.Equal(t, "SELECT a FROM b WHERE c = $1 AND d > $2 LIMIT $3",
+		rebind(postgres, "SELECT a FROM b WHERE c = ? AND d > ? LIMIT ?"))
+	assert.Equal(t, `SELECT '?', "a?", $1 -- ?
+		/* ? */ FROM b WHERE c = $2`,
+		rebind(postgres, `SELECT '?', "a?", ? -- ?
+		/* ? */ FROM b WHERE c = ?`))
+	assert.Equal(t, "SELECT 'it''s', $1", rebind(postgres, "SELECT 'it''s', ?"))
+
+	assert.Equal(t, "SELECT ? FROM b", rebind(mysqlDialect{}, "SELECT ? FROM b"))
+	assert.Equal(t, "SELECT ? FROM b", rebind(sqliteDialect{}, "SELECT ? FROM b"))
+}
+
+func TestPaginate(t *testing.T) {
+	const query string = "SELECT id FROM t ORDER BY id"
+
+	assert.Equal(t, query+" LIMIT ? FOR UPDATE", mysqlDialect{}.Paginate(query, true))
+	assert.Equal(t, query+" LIMIT ? FOR UPDATE", postgresDialect{}.Paginate(query, true))
+	assert.Equal(t, query+" LIMIT ?", postgresDialect{}.Paginate(query, false))
+	assert.Equal(t, query+" LIMIT ?", sqliteDialect{}.Paginate(query, true))
+}
+
+func TestSQLite(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.DatabaseConfig{
+		Driver:          DriverSQLite,
+		Name:            filepath.Join(t.TempDir(), "test.db"),
+		MaxConnection:   2,
+		ConnectAttempts: 1,
+	}
+
+	db, err := Open(ctx, cfg)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, Migrate(ctx, db))
+	require.NoError(t, CheckMigrations(db)(ctx))
+
+	_, err = db.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	require.NoError(t, err)
+
+	_, err = db.ExecContext(ctx, "INSERT INTO t (id, name) VALUES (?, ?)", 1, "first")
+	require.NoError(t, err)
+
+	var name string
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT name FROM t WHERE id = ?", 1).Scan(&name))
+	assert.Equal(t, "first", name)
+
+	_, err = db.ExecContext(ctx, "INSERT INTO t (id, name) VALUES (?, ?)", 1, "second")
+	assert.True(t, IsDuplicateKey(err), "got %v", err)
+	assert.False(t, IsDuplicateKey(errors.New("1062")))
+	assert.False(t, IsDuplicateKey(nil))
+}
+
+func TestUnknownDriver(t *testing.T) {
+	_, err := Open(context.Background(), config.DatabaseConfig{Driver: "oracle"})
+
+	assert.ErrorContains(t, err, `unknown database driver "oracle"`)
+}
@@ -0,0 +1,296 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// Hook observes every statement sent to the database. It is called before
+// the statement runs and returns a function called with the outcome once the
+// statement, including reading all of its rows, is done. The outcome may be
+// driver.ErrSkip when the driver declined the statement and database/sql
+// retries it through a prepared statement; hooks should then discard it.
+type Hook func(ctx context.Context, query string, args []driver.NamedValue) func(err error)
+
+var hooksMu sync.RWMutex
+var hooks []Hook
+
+// AddHook registers hook for every statement run from now on.
+func AddHook(hook Hook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	hooks = append(hooks, hook)
+}
+
+func runHooks(ctx context.Context, query string, args []driver.NamedValue) func(err error) {
+	hooksMu.RLock()
+	current := hooks
+	hooksMu.RUnlock()
+
+	finishes := make([]func(error), len(current))
+	for i, hook := range current {
+		finishes[i] = hook(ctx, query, args)
+	}
+
+	return func(err error) {
+		for i := len(finishes) - 1; i >= 0; i-- {
+			finishes[i](err)
+		}
+	}
+}
+
+// hookedConnector wraps a driver so every statement is rewritten for the
+// dialect and passes through the registered hooks.
+type hookedConnector struct {
+	driver.Connector
+	dialect Dialect
+}
+
+func (c hookedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hookedConn{Conn: conn, dialect: c.dialect}, nil
+}
+
+type hookedConn struct {
+	driver.Conn
+	dialect Dialect
+}
+
+func (c *hookedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *hookedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+
+	query = rebind(c.dialect, query)
+
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &hookedStmt{Stmt: stmt, conn: c, query: query}, nil
+}
+
+func (c *hookedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+
+	return c.Conn.Begin()
+}
+
+func (c *hookedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	query = rebind(c.dialect, query)
+	finish := runHooks(withSystem(ctx, c.dialect), query, args)
+	result, err := execer.ExecContext(ctx, query, args)
+	finish(err)
+
+	return result, err
+}
+
+func (c *hookedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	query = rebind(c.dialect, query)
+	finish := runHooks(withSystem(ctx, c.dialect), query, args)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		finish(err)
+
+		return nil, err
+	}
+
+	return &hookedRows{Rows: rows, finish: finish}, nil
+}
+
+func (c *hookedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (c *hookedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+func (c *hookedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+
+	return true
+}
+
+func (c *hookedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+
+	return driver.ErrSkip
+}
+
+type hookedStmt struct {
+	driver.Stmt
+	conn  *hookedConn
+	query string
+}
+
+func (s *hookedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := s.Stmt.(driver.StmtExecContext)
+	if !ok {
+		return nil, errors.New("database: driver statement does not support ExecContext")
+	}
+
+	finish := runHooks(withSystem(ctx, s.conn.dialect), s.query, args)
+	result, err := execer.ExecContext(ctx, args)
+	finish(err)
+
+	return result, err
+}
+
+func (s *hookedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := s.Stmt.(driver.StmtQueryContext)
+	if !ok {
+		return nil, errors.New("database: driver statement does not support QueryContext")
+	}
+
+	finish := runHooks(withSystem(ctx, s.conn.dialect), s.query, args)
+	rows, err := queryer.QueryContext(ctx, args)
+	if err != nil {
+		finish(err)
+
+		return nil, err
+	}
+
+	return &hookedRows{Rows: rows, finish: finish}, nil
+}
+
+// CheckNamedValue prefers the statement's checker and falls back to the
+// connection's, the order database/sql itself uses.
+func (s *hookedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+
+	return s.conn.CheckNamedValue(nv)
+}
+
+// hookedRows reports the statement as finished when its rows are closed, so
+// hooks see the time spent reading them and any error hit while doing so.
+type hookedRows struct {
+	driver.Rows
+	finish func(error)
+	err    error
+}
+
+func (r *hookedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.err = err
+	}
+
+	return err
+}
+
+func (r *hookedRows) Close() error {
+	err := r.Rows.Close()
+
+	if r.finish != nil {
+		if r.err != nil {
+			r.finish(r.err)
+		} else {
+			r.finish(err)
+		}
+
+		r.finish = nil
+	}
+
+	return err
+}
+
+func (r *hookedRows) HasNextResultSet() bool {
+	if next, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return next.HasNextResultSet()
+	}
+
+	return false
+}
+
+func (r *hookedRows) NextResultSet() error {
+	if next, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return next.NextResultSet()
+	}
+
+	return io.EOF
+}
+
+func (r *hookedRows) ColumnTypeScanType(index int) reflect.Type {
+	if typer, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return typer.ColumnTypeScanType(index)
+	}
+
+	return reflect.TypeFor[any]()
+}
+
+func (r *hookedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if typer, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return typer.ColumnTypeDatabaseTypeName(index)
+	}
+
+	return ""
+}
+
+func (r *hookedRows) ColumnTypeNullable(index int) (bool, bool) {
+	if typer, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return typer.ColumnTypeNullable(index)
+	}
+
+	return false, false
+}
+
+func (r *hookedRows) ColumnTypeLength(index int) (int64, bool) {
+	if typer, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return typer.ColumnTypeLength(index)
+	}
+
+	return 0, false
+}
+
+func (r *hookedRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if typer, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return typer.ColumnTypePrecisionScale(index)
+	}
+
+	return 0, 0, false
+}
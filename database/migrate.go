@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles holds a directory of migrations per dialect. MySQL ones
+// start from the existing customer and address tables, the others create
+// them.
+//
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+func loadMigrations(dialect Dialect) ([]migration, error) {
+	var migrations []migration
+
+	dir := "migrations/" + dialect.Name()
+
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		versionStr, _, found := strings.Cut(entry.Name(), "_")
+		if !found {
+			return nil, fmt.Errorf("migration %s is not named <version>_<name>.sql", entry.Name())
+		}
+
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has an invalid version: %w", entry.Name(), err)
+		}
+
+		content, err := migrationFiles.ReadFile(dir + "/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{version: version, name: entry.Name(), sql: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// LatestVersion is the version the schema is at once every embedded
+// migration of dialect has been applied.
+func LatestVersion(dialect Dialect) int {
+	migrations, err := loadMigrations(dialect)
+	if err != nil || len(migrations) == 0 {
+		return 0
+	}
+
+	return migrations[len(migrations)-1].version
+}
+
+// Version reports the highest migration applied to db.
+func Version(ctx context.Context, db *sql.DB) (int, error) {
+	var version sql.NullInt64
+
+	err := db.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(version.Int64), nil
+}
+
+// Migrate applies, in order, every embedded migration newer than the
+// version recorded in schema_migrations.
+func Migrate(ctx context.Context, db *DB) error {
+	const createTable string = `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT NOT NULL,
+			applied_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (version)
+		)`
+
+	_, err := db.ExecContext(ctx, createTable)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	current, err := Version(ctx, db.DB)
+	if err != nil {
+		return err
+	}
+
+	migrations, err := loadMigrations(db.Dialect)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		for _, statement := range strings.Split(m.sql, ";") {
+			if strings.TrimSpace(statement) == "" {
+				continue
+			}
+
+			_, err = db.ExecContext(ctx, statement)
+			if err != nil {
+				return fmt.Errorf("migration %s failed: %w", m.name, err)
+			}
+		}
+
+		_, err = db.ExecContext(ctx, "INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)", m.version, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", m.name, err)
+		}
+
+		log.Infof("migration %s applied", m.name)
+	}
+
+	return nil
+}
+
+// CheckMigrations is a readiness check failing until the schema is at
+// LatestVersion.
+func CheckMigrations(db *DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		version, err := Version(ctx, db.DB)
+		if err != nil {
+			return err
+		}
+
+		if latest := LatestVersion(db.Dialect); version != latest {
+			return fmt.Errorf("schema is at version %d, expected %d", version, latest)
+		}
+
+		return nil
+	}
+}
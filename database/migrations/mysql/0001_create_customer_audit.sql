@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS customer_audit (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	customer_id SMALLINT UNSIGNED NOT NULL,
+	action VARCHAR(20) NOT NULL,
+	actor VARCHAR(100) NOT NULL,
+	detail JSON NULL,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (id),
+	KEY idx_customer_audit_customer_id (customer_id)
+);
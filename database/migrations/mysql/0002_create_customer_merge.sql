@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS customer_merge (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	survivor_id SMALLINT UNSIGNED NOT NULL,
+	duplicate_id SMALLINT UNSIGNED NOT NULL,
+	duplicate_snapshot JSON NOT NULL,
+	merged_by VARCHAR(100) NOT NULL,
+	merged_at DATETIME NOT NULL,
+	PRIMARY KEY (id),
+	KEY idx_customer_merge_survivor_id (survivor_id),
+	KEY idx_customer_merge_duplicate_id (duplicate_id)
+);
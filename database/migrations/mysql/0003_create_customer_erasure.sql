@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS customer_erasure (
+	id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+	customer_id SMALLINT UNSIGNED NOT NULL,
+	reason VARCHAR(255) NOT NULL,
+	erased_by VARCHAR(100) NOT NULL,
+	erased_at DATETIME NOT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY uq_customer_erasure_customer_id (customer_id)
+);
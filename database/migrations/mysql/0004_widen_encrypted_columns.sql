@@ -0,0 +1,15 @@
+ALTER TABLE customer
+	MODIFY first_name VARCHAR(512) NOT NULL,
+	MODIFY last_name VARCHAR(512) NOT NULL,
+	MODIFY email VARCHAR(512) NULL,
+	ADD COLUMN email_bidx CHAR(64) NULL,
+	ADD UNIQUE KEY uq_customer_email_bidx (email_bidx);
+
+ALTER TABLE address
+	MODIFY address VARCHAR(512) NOT NULL,
+	MODIFY address2 VARCHAR(512) NULL,
+	MODIFY district VARCHAR(512) NOT NULL,
+	MODIFY postal_code VARCHAR(512) NULL;
+
+ALTER TABLE customer_merge
+	MODIFY duplicate_snapshot TEXT NOT NULL;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS address (
+	id SERIAL NOT NULL,
+	address VARCHAR(512) NOT NULL,
+	address2 VARCHAR(512) NULL,
+	district VARCHAR(512) NOT NULL,
+	city_id INTEGER NOT NULL,
+	postal_code VARCHAR(512) NULL,
+	last_update TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS customer (
+	id SERIAL NOT NULL,
+	first_name VARCHAR(512) NOT NULL,
+	last_name VARCHAR(512) NOT NULL,
+	email VARCHAR(512) NULL,
+	email_bidx CHAR(64) NULL,
+	address_id INTEGER NOT NULL REFERENCES address (id),
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL,
+	created_by VARCHAR(100) NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT uq_customer_email UNIQUE (email),
+	CONSTRAINT uq_customer_email_bidx UNIQUE (email_bidx)
+);
+
+-- New customers are created at address 1 until they set their own.
+INSERT INTO address (address, district, city_id) VALUES ('', '', 0);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS customer_audit (
+	id BIGSERIAL NOT NULL,
+	customer_id INTEGER NOT NULL,
+	action VARCHAR(20) NOT NULL,
+	actor VARCHAR(100) NOT NULL,
+	detail JSON NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_audit_customer_id ON customer_audit (customer_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS customer_merge (
+	id BIGSERIAL NOT NULL,
+	survivor_id INTEGER NOT NULL,
+	duplicate_id INTEGER NOT NULL,
+	duplicate_snapshot TEXT NOT NULL,
+	merged_by VARCHAR(100) NOT NULL,
+	merged_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_merge_survivor_id ON customer_merge (survivor_id);
+
+CREATE INDEX IF NOT EXISTS idx_customer_merge_duplicate_id ON customer_merge (duplicate_id);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS customer_erasure (
+	id BIGSERIAL NOT NULL,
+	customer_id INTEGER NOT NULL,
+	reason VARCHAR(255) NOT NULL,
+	erased_by VARCHAR(100) NOT NULL,
+	erased_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT uq_customer_erasure_customer_id UNIQUE (customer_id)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS customer_audit (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	customer_id INTEGER NOT NULL,
+	action VARCHAR(20) NOT NULL,
+	actor VARCHAR(100) NOT NULL,
+	detail TEXT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_audit_customer_id ON customer_audit (customer_id);
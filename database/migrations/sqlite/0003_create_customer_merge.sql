@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS customer_merge (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	survivor_id INTEGER NOT NULL,
+	duplicate_id INTEGER NOT NULL,
+	duplicate_snapshot TEXT NOT NULL,
+	merged_by VARCHAR(100) NOT NULL,
+	merged_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_merge_survivor_id ON customer_merge (survivor_id);
+
+CREATE INDEX IF NOT EXISTS idx_customer_merge_duplicate_id ON customer_merge (duplicate_id);
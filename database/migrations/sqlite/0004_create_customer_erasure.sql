@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS customer_erasure (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	customer_id INTEGER NOT NULL,
+	reason VARCHAR(255) NOT NULL,
+	erased_by VARCHAR(100) NOT NULL,
+	erased_at DATETIME NOT NULL,
+	CONSTRAINT uq_customer_erasure_customer_id UNIQUE (customer_id)
+);
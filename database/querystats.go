@@ -0,0 +1,313 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mmiftahrzki/customer/logger"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxQuerySamples is how many of the latest durations of a statement
+	// the percentiles are computed over.
+	maxQuerySamples int = 1024
+	// maxFingerprints bounds the statements tracked apart; any others are
+	// counted together under otherFingerprint.
+	maxFingerprints  int    = 500
+	otherFingerprint string = "other"
+)
+
+var placeholderList = regexp.MustCompile(`\?(\s*,\s*\?)+`)
+
+// queryStat aggregates the runs of the statements sharing a fingerprint.
+type queryStat struct {
+	mu        sync.Mutex
+	statement string
+	count     uint64
+	errors    uint64
+	total     time.Duration
+	max       time.Duration
+	samples   []time.Duration
+	next      int
+}
+
+// queryStats times every statement, keeping aggregates per fingerprint and
+// logging those slower than the threshold.
+type queryStats struct {
+	mu        sync.RWMutex
+	stats     map[string]*queryStat
+	threshold atomic.Int64
+}
+
+var stats = &queryStats{stats: map[string]*queryStat{}}
+
+func init() {
+	AddHook(stats.observe)
+}
+
+// SetSlowQueryThreshold sets how long a statement may take before it is
+// logged as slow. Zero turns the log off; statistics are kept regardless.
+func SetSlowQueryThreshold(threshold time.Duration) {
+	stats.threshold.Store(int64(threshold))
+}
+
+func (s *queryStats) observe(ctx context.Context, query string, args []driver.NamedValue) func(err error) {
+	start := time.Now()
+
+	return func(err error) {
+		if errors.Is(err, driver.ErrSkip) {
+			return
+		}
+
+		elapsed := time.Since(start)
+		statement := normalizeQuery(query)
+		fingerprint := fingerprintOf(statement)
+
+		s.record(fingerprint, statement, elapsed, err)
+
+		threshold := time.Duration(s.threshold.Load())
+		if threshold <= 0 || elapsed < threshold {
+			return
+		}
+
+		entry := logger.WithContext(ctx, log).WithFields(logrus.Fields{
+			"duration_ms": float64(elapsed.Microseconds()) / 1000,
+			"fingerprint": fingerprint,
+			"statement":   statement,
+			"args":        redactArgs(args),
+		})
+		if err != nil {
+			entry = entry.WithError(err)
+		}
+
+		entry.Warn("slow query")
+	}
+}
+
+func (s *queryStats) record(fingerprint string, statement string, elapsed time.Duration, err error) {
+	s.mu.RLock()
+	stat, ok := s.stats[fingerprint]
+	s.mu.RUnlock()
+
+	if !ok {
+		s.mu.Lock()
+		stat, ok = s.stats[fingerprint]
+		if !ok {
+			if len(s.stats) >= maxFingerprints {
+				fingerprint, statement = otherFingerprint, ""
+			}
+
+			stat, ok = s.stats[fingerprint]
+			if !ok {
+				stat = &queryStat{statement: statement}
+				s.stats[fingerprint] = stat
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+
+	stat.count++
+	if err != nil {
+		stat.errors++
+	}
+
+	stat.total += elapsed
+	stat.max = max(stat.max, elapsed)
+
+	if len(stat.samples) < maxQuerySamples {
+		stat.samples = append(stat.samples, elapsed)
+	} else {
+		stat.samples[stat.next] = elapsed
+		stat.next = (stat.next + 1) % maxQuerySamples
+	}
+}
+
+// snapshot returns the aggregates of every fingerprint, the statements the
+// database spent the most time on first.
+func (s *queryStats) snapshot() []modelQueryStat {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	models := make([]modelQueryStat, 0, len(s.stats))
+	for fingerprint, stat := range s.stats {
+		stat.mu.Lock()
+		samples := slices.Clone(stat.samples)
+		model := modelQueryStat{
+			Fingerprint: fingerprint,
+			Statement:   stat.statement,
+			Count:       stat.count,
+			Errors:      stat.errors,
+			TotalMs:     milliseconds(stat.total),
+			MeanMs:      milliseconds(stat.total / time.Duration(stat.count)),
+			MaxMs:       milliseconds(stat.max),
+		}
+		stat.mu.Unlock()
+
+		slices.Sort(samples)
+		model.P50Ms = milliseconds(percentile(samples, 0.50))
+		model.P99Ms = milliseconds(percentile(samples, 0.99))
+
+		models = append(models, model)
+	}
+
+	slices.SortFunc(models, func(a, b modelQueryStat) int {
+		if a.TotalMs != b.TotalMs {
+			if a.TotalMs > b.TotalMs {
+				return -1
+			}
+
+			return 1
+		}
+
+		return strings.Compare(a.Fingerprint, b.Fingerprint)
+	})
+
+	return models
+}
+
+func (s *queryStats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats = map[string]*queryStat{}
+}
+
+// percentile returns the nearest-rank percentile p of sorted.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// normalizeQuery reduces a statement to its shape, so runs differing only in
+// their values share a fingerprint: literals and placeholders become ?,
+// lists of them collapse into one, comments are dropped and whitespace is
+// squeezed. Quoted identifiers are kept.
+func normalizeQuery(query string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case c == '\'':
+			i = stringEnd(query, i)
+			b.WriteByte('?')
+		case c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				b.WriteString(query[i:])
+				i = len(query)
+			} else {
+				b.WriteString(query[i : i+end+2])
+				i += end + 1
+			}
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end - 1
+			}
+
+			b.WriteByte(' ')
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 3
+			}
+
+			b.WriteByte(' ')
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			for i+1 < len(query) && isDigit(query[i+1]) {
+				i++
+			}
+
+			b.WriteByte('?')
+		case isDigit(c) && (i == 0 || !isWordByte(query[i-1])):
+			for i+1 < len(query) && (isDigit(query[i+1]) || query[i+1] == '.') {
+				i++
+			}
+
+			b.WriteByte('?')
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return placeholderList.ReplaceAllString(strings.Join(strings.Fields(b.String()), " "), "?, ...")
+}
+
+// stringEnd returns the index of the quote closing the string literal
+// opened at start, skipping quotes escaped by doubling or a backslash.
+func stringEnd(query string, start int) int {
+	for i := start + 1; i < len(query); i++ {
+		switch {
+		case query[i] == '\\':
+			i++
+		case query[i] == '\'' && i+1 < len(query) && query[i+1] == '\'':
+			i++
+		case query[i] == '\'':
+			return i
+		}
+	}
+
+	return len(query)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'z')
+}
+
+// fingerprintOf is a short, stable name for a normalized statement.
+func fingerprintOf(statement string) string {
+	h := fnv.New64a()
+	h.Write([]byte(statement))
+
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// redactArgs describes the arguments of a statement by type only, as their
+// values may carry personal data.
+func redactArgs(args []driver.NamedValue) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		if arg.Value == nil {
+			redacted[i] = "NULL"
+
+			continue
+		}
+
+		redacted[i] = fmt.Sprintf("%T", arg.Value)
+	}
+
+	return redacted
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"net/http"
+
+	"github.com/mmiftahrzki/customer/logger"
+	"github.com/mmiftahrzki/customer/responses"
+)
+
+// modelQueryStat is what the admin endpoint reports about the statements
+// sharing a fingerprint. Durations are in milliseconds; the percentiles
+// cover the latest runs only.
+type modelQueryStat struct {
+	Fingerprint string  `json:"fingerprint"`
+	Statement   string  `json:"statement"`
+	Count       uint64  `json:"count"`
+	Errors      uint64  `json:"errors"`
+	TotalMs     float64 `json:"total_ms"`
+	MeanMs      float64 `json:"mean_ms"`
+	P50Ms       float64 `json:"p50_ms"`
+	P99Ms       float64 `json:"p99_ms"`
+	MaxMs       float64 `json:"max_ms"`
+}
+
+type statsHandler struct{}
+
+// NewStatsHandler returns the handlers of the admin endpoint reporting and
+// resetting the statistics kept per query fingerprint.
+func NewStatsHandler() statsHandler {
+	return statsHandler{}
+}
+
+func (h statsHandler) GetQueryStats(w http.ResponseWriter, r *http.Request) {
+	var res responses.GetMultipleResponse[modelQueryStat]
+
+	res.Data = stats.snapshot()
+
+	responses.WithJson(w, http.StatusOK, res)
+}
+
+func (h statsHandler) DeleteQueryStats(w http.ResponseWriter, r *http.Request) {
+	stats.reset()
+
+	logger.WithContext(r.Context(), log).Info("query statistics reset")
+
+	w.WriteHeader(http.StatusNoContent)
+}
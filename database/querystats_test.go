@@ -0,0 +1,59 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	assert.Equal(t, "SELECT a FROM b WHERE c = ? AND d IN (?, ...) LIMIT ?",
+		normalizeQuery("SELECT a FROM b\n\tWHERE c = 'x''' AND d IN (1, 2.5, 3) -- comment\n LIMIT ?"))
+	assert.Equal(t, "SELECT a FROM b WHERE c = ? AND d = ?",
+		normalizeQuery("SELECT a FROM b /* ? */ WHERE c = $1 AND d = $12"))
+	assert.Equal(t, "INSERT INTO t2 (a, `b1`) VALUES (?, ...)",
+		normalizeQuery("INSERT INTO t2 (a, `b1`) VALUES (?, ?, ?)"))
+	assert.Equal(t, normalizeQuery("SELECT * FROM t WHERE id = 1"), normalizeQuery("SELECT * FROM t WHERE id = 42"))
+}
+
+func TestRedactArgs(t *testing.T) {
+	args := []driver.NamedValue{{Value: "mary@example.com"}, {Value: int64(1)}, {Value: nil}}
+
+	assert.Equal(t, []string{"string", "int64", "NULL"}, redactArgs(args))
+}
+
+func TestQueryStats(t *testing.T) {
+	s := &queryStats{stats: map[string]*queryStat{}}
+	statement := normalizeQuery("SELECT * FROM t WHERE id = ?")
+	fingerprint := fingerprintOf(statement)
+
+	for i := 1; i <= 100; i++ {
+		var err error
+		if i%25 == 0 {
+			err = errors.New("failure")
+		}
+
+		s.record(fingerprint, statement, time.Duration(i)*time.Millisecond, err)
+	}
+	s.record(fingerprintOf("SELECT ?"), "SELECT ?", time.Millisecond, nil)
+
+	snapshot := s.snapshot()
+	require.Len(t, snapshot, 2)
+
+	stat := snapshot[0]
+	assert.Equal(t, fingerprint, stat.Fingerprint)
+	assert.Equal(t, statement, stat.Statement)
+	assert.Equal(t, uint64(100), stat.Count)
+	assert.Equal(t, uint64(4), stat.Errors)
+	assert.Equal(t, 5050.0, stat.TotalMs)
+	assert.Equal(t, 50.0, stat.P50Ms)
+	assert.Equal(t, 99.0, stat.P99Ms)
+	assert.Equal(t, 100.0, stat.MaxMs)
+
+	s.reset()
+	assert.Empty(t, s.snapshot())
+}
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mmiftahrzki/customer/config"
+	"github.com/mmiftahrzki/customer/metrics"
+)
+
+var errNotReplicating = errors.New("replication is not running")
+
+// DB is the primary pool, embedded so it can be used wherever a *sql.DB is
+// expected, the dialect of the database, the read replicas Reader balances
+// reads over and the policy Retry follows.
+type DB struct {
+	*sql.DB
+	Dialect  Dialect
+	retry    retryPolicy
+	replicas []*replica
+	next     atomic.Uint64
+	stop     context.CancelFunc
+	done     sync.WaitGroup
+}
+
+// replica is a read replica pool and whether it is currently in rotation.
+type replica struct {
+	addr    string
+	db      *sql.DB
+	dialect Dialect
+	healthy atomic.Bool
+	checked bool
+}
+
+// Reader returns the pool a read should run on: the transaction-free
+// primary after a write made with ctx, or when ctx asks for it, and
+// otherwise the next healthy replica. Without a healthy replica it falls
+// back to the primary.
+func (db *DB) Reader(ctx context.Context) *sql.DB {
+	if len(db.replicas) == 0 || readsPrimary(ctx) {
+		return db.DB
+	}
+
+	healthy := make([]*sql.DB, 0, len(db.replicas))
+	for _, r := range db.replicas {
+		if r.healthy.Load() {
+			healthy = append(healthy, r.db)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return db.DB
+	}
+
+	return healthy[db.next.Add(1)%uint64(len(healthy))]
+}
+
+// WithReplicas returns a DB sharing db's primary that balances reads over
+// pools instead of the configured replicas. The pools are always in
+// rotation and never checked, so they can stand in for replicas in tests,
+// lagging ones included. Closing the returned DB closes the pools and the
+// primary.
+func (db *DB) WithReplicas(pools ...*sql.DB) *DB {
+	replicas := make([]*replica, 0, len(pools))
+	for _, pool := range pools {
+		r := &replica{db: pool, dialect: db.Dialect, checked: true}
+		r.healthy.Store(true)
+		replicas = append(replicas, r)
+	}
+
+	return &DB{DB: db.DB, Dialect: db.Dialect, retry: db.retry, replicas: replicas}
+}
+
+// Close stops the lag checks and closes the replicas, then the primary.
+func (db *DB) Close() error {
+	if db.stop != nil {
+		db.stop()
+		db.done.Wait()
+	}
+
+	errs := []error{}
+	for _, r := range db.replicas {
+		errs = append(errs, r.db.Close())
+	}
+
+	errs = append(errs, db.DB.Close())
+
+	return errors.Join(errs...)
+}
+
+// replicaAddr completes a configured replica address with the primary port.
+func replicaAddr(replica string, port uint16) string {
+	if _, _, err := net.SplitHostPort(replica); err == nil {
+		return replica
+	}
+
+	return net.JoinHostPort(replica, strconv.Itoa(int(port)))
+}
+
+// openReplicas opens a pool per configured replica. They are not required
+// to be up: one that is not stays out of rotation until a check finds it.
+func openReplicas(dialect Dialect, cfg config.DatabaseConfig) ([]*replica, error) {
+	replicas := []*replica{}
+
+	for _, addr := range cfg.Replicas {
+		addr = replicaAddr(addr, port(dialect, cfg))
+
+		db, err := openPool(dialect, cfg, addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid connection settings for %s: %w", addr, err)
+		}
+
+		replicas = append(replicas, &replica{addr: addr, db: db, dialect: dialect})
+	}
+
+	return replicas, nil
+}
+
+// check takes the replica out of rotation when it does not answer, does not
+// replicate or lags more than maxLag, and back in once it has recovered.
+// Changes are logged.
+func (r *replica) check(ctx context.Context, timeout time.Duration, maxLag time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	lag, err := r.dialect.replicationLag(ctx, r.db)
+	if err == nil && maxLag > 0 && lag > maxLag {
+		err = fmt.Errorf("lagging %s behind, more than %s", lag, maxLag)
+	}
+
+	healthy := err == nil
+	if r.healthy.Swap(healthy) == healthy && r.checked {
+		return
+	}
+
+	r.checked = true
+
+	if healthy {
+		log.Infof("replica %s back in rotation, lagging %s behind", r.addr, lag)
+	} else {
+		log.Warnf("replica %s out of rotation: %v", r.addr, err)
+	}
+}
+
+// watchReplicas checks the replicas once, so New returns with the healthy
+// ones in rotation, then every interval until db is closed.
+func (db *DB) watchReplicas(cfg config.DatabaseConfig) {
+	ctx, cancel := context.WithCancel(context.Background())
+	db.stop = cancel
+
+	timeout := min(cfg.ReplicaCheckInterval, cfg.ConnectTimeout)
+	if timeout <= 0 {
+		timeout = cfg.ReplicaCheckInterval
+	}
+
+	checkAll := func() {
+		var wg sync.WaitGroup
+
+		for _, r := range db.replicas {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				r.check(ctx, timeout, cfg.ReplicaMaxLag)
+			}()
+		}
+
+		wg.Wait()
+	}
+
+	checkAll()
+
+	db.done.Add(1)
+
+	go func() {
+		defer db.done.Done()
+
+		ticker := time.NewTicker(cfg.ReplicaCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				checkAll()
+			}
+		}
+	}()
+
+	registry := metrics.GetRegistry()
+	registry.NewGaugeFunc("db_replicas", "Configured read replicas.", func() float64 {
+		return float64(len(db.replicas))
+	})
+	registry.NewGaugeFunc("db_replicas_healthy", "Read replicas currently in rotation.", func() float64 {
+		healthy := 0
+		for _, r := range db.replicas {
+			if r.healthy.Load() {
+				healthy++
+			}
+		}
+
+		return float64(healthy)
+	})
+}
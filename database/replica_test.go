@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDB(healthy ...bool) *DB {
+	db := &DB{DB: sql.OpenDB(hookedConnector{})}
+
+	for _, h := range healthy {
+		r := &replica{db: sql.OpenDB(hookedConnector{})}
+		r.healthy.Store(h)
+		db.replicas = append(db.replicas, r)
+	}
+
+	return db
+}
+
+func TestReaderWithoutReplicasUsesPrimary(t *testing.T) {
+	db := newTestDB()
+
+	assert.Same(t, db.DB, db.Reader(context.Background()))
+}
+
+func TestReaderSkipsUnhealthyReplicas(t *testing.T) {
+	db := newTestDB(true, false, true)
+	ctx := context.Background()
+
+	seen := map[*sql.DB]int{}
+	for range 6 {
+		seen[db.Reader(ctx)]++
+	}
+
+	assert.Equal(t, 3, seen[db.replicas[0].db])
+	assert.Equal(t, 3, seen[db.replicas[2].db])
+	assert.Zero(t, seen[db.replicas[1].db])
+	assert.Zero(t, seen[db.DB])
+}
+
+func TestReaderFallsBackToPrimary(t *testing.T) {
+	db := newTestDB(false, false)
+
+	assert.Same(t, db.DB, db.Reader(context.Background()))
+}
+
+func TestReaderAfterWriteUsesPrimary(t *testing.T) {
+	db := newTestDB(true)
+
+	ctx := NewSession(context.Background())
+	assert.Same(t, db.replicas[0].db, db.Reader(ctx))
+
+	MarkWritten(ctx)
+	assert.Same(t, db.DB, db.Reader(ctx))
+	assert.Same(t, db.DB, db.Reader(WithPrimary(context.Background())))
+
+	// Without a session a write cannot be remembered.
+	MarkWritten(context.Background())
+	assert.Same(t, db.replicas[0].db, db.Reader(context.Background()))
+}
+
+func TestWithReplicas(t *testing.T) {
+	db := newTestDB(false)
+	pool := sql.OpenDB(hookedConnector{})
+
+	withReplicas := db.WithReplicas(pool)
+	assert.Same(t, db.DB, withReplicas.DB)
+	assert.Same(t, pool, withReplicas.Reader(context.Background()), "stand-in replicas should always be in rotation")
+	assert.Same(t, db.DB, withReplicas.Reader(WithPrimary(context.Background())))
+}
+
+func TestReplicaAddr(t *testing.T) {
+	assert.Equal(t, "replica-1:3306", replicaAddr("replica-1", 3306))
+	assert.Equal(t, "replica-1:3307", replicaAddr("replica-1:3307", 3306))
+}
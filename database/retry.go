@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/mmiftahrzki/customer/logger"
+	"github.com/mmiftahrzki/customer/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// Reasons a unit of work is retried, as reported by the dialects.
+const (
+	retryDeadlock      string = "deadlock"
+	retryLockTimeout   string = "lock_timeout"
+	retrySerialization string = "serialization_failure"
+)
+
+var txRetries = metrics.GetRegistry().NewCounterVec("db_tx_retries_total",
+	"Units of work run again after a transient failure, by operation and reason.", "operation", "reason")
+
+var txRetriesExhausted = metrics.GetRegistry().NewCounterVec("db_tx_retries_exhausted_total",
+	"Units of work given up on while still failing transiently, by operation and reason.", "operation", "reason")
+
+var txAttempts = metrics.GetRegistry().NewHistogramVec("db_tx_attempts",
+	"Times a unit of work was run before it succeeded or was given up on, by operation.",
+	[]float64{1, 2, 3, 5, 10}, "operation")
+
+// retryPolicy bounds how often, and how far apart, Retry runs a unit of work.
+type retryPolicy struct {
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// Retry runs fn, the whole of a unit of work such as a transaction from
+// begin to commit, and runs it again while it fails with a deadlock, lock
+// wait timeout or serialization failure. Each retry waits a random time
+// below a bound that doubles after every attempt. It gives up, returning
+// the last error, once the attempts are used up or the wait would run past
+// the deadline of ctx. operation labels the logs and metrics.
+//
+// fn must not be part of an outer transaction: the failure has rolled that
+// back too, so only the outermost unit of work can be retried.
+func (db *DB) Retry(ctx context.Context, operation string, fn func(ctx context.Context) error) error {
+	attempts := max(db.retry.attempts, 1)
+	bound := db.retry.backoff
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+
+		reason := retryReason(err)
+		if reason == "" {
+			txAttempts.Observe(float64(attempt), operation)
+
+			return err
+		}
+
+		entry := logger.WithContext(ctx, log).WithFields(logrus.Fields{
+			"operation": operation,
+			"attempt":   attempt,
+			"reason":    reason,
+		})
+
+		delay := jitter(bound)
+		if deadline, ok := ctx.Deadline(); attempt == attempts || (ok && time.Until(deadline) <= delay) {
+			txAttempts.Observe(float64(attempt), operation)
+			txRetriesExhausted.Inc(operation, reason)
+			entry.WithError(err).Error("giving up on transient database failure")
+
+			return fmt.Errorf("%s failed after %d attempts: %w", operation, attempt, err)
+		}
+
+		txRetries.Inc(operation, reason)
+		entry.WithError(err).Warnf("retrying in %s", delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			txAttempts.Observe(float64(attempt), operation)
+
+			return err
+		case <-timer.C:
+		}
+
+		bound = min(bound*2, max(db.retry.maxBackoff, db.retry.backoff))
+	}
+}
+
+// jitter picks a random wait below bound, spreading out the retries of the
+// transactions that ran into each other.
+func jitter(bound time.Duration) time.Duration {
+	if bound <= 0 {
+		return 0
+	}
+
+	return rand.N(bound)
+}
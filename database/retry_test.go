@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	asd "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(&asd.MySQLError{Number: 1213}))
+	assert.True(t, IsRetryable(fmt.Errorf("wrapped: %w", &asd.MySQLError{Number: 1205})))
+	assert.True(t, IsRetryable(&pq.Error{Code: "40P01"}))
+	assert.True(t, IsRetryable(&pq.Error{Code: "40001"}))
+	assert.True(t, IsRetryable(sqlite3.Error{Code: sqlite3.ErrBusy}))
+
+	assert.False(t, IsRetryable(&asd.MySQLError{Number: 1062}))
+	assert.False(t, IsRetryable(&pq.Error{Code: "23505"}))
+	assert.False(t, IsRetryable(errors.New("1213")))
+	assert.False(t, IsRetryable(nil))
+}
+
+func TestRetry(t *testing.T) {
+	deadlock := &asd.MySQLError{Number: 1213}
+	db := &DB{retry: retryPolicy{attempts: 3, backoff: time.Millisecond, maxBackoff: 2 * time.Millisecond}}
+
+	t.Run("succeeds after transient failures", func(t *testing.T) {
+		calls := 0
+		err := db.Retry(context.Background(), "test", func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return deadlock
+			}
+
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		calls := 0
+		err := db.Retry(context.Background(), "test", func(ctx context.Context) error {
+			calls++
+
+			return deadlock
+		})
+
+		assert.ErrorIs(t, err, deadlock)
+		assert.ErrorContains(t, err, "after 3 attempts")
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		calls := 0
+		failure := errors.New("failure")
+		err := db.Retry(context.Background(), "test", func(ctx context.Context) error {
+			calls++
+
+			return failure
+		})
+
+		assert.ErrorIs(t, err, failure)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("stops at the deadline", func(t *testing.T) {
+		slow := &DB{retry: retryPolicy{attempts: 5, backoff: time.Hour, maxBackoff: time.Hour}}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		calls := 0
+		start := time.Now()
+		err := slow.Retry(ctx, "test", func(ctx context.Context) error {
+			calls++
+
+			return deadlock
+		})
+
+		assert.ErrorIs(t, err, deadlock)
+		assert.Less(t, calls, 5)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+
+	"github.com/mmiftahrzki/customer/tracing"
+)
+
+func init() {
+	AddHook(traceStatement)
+}
+
+// traceStatement records a client span per SQL statement. Arguments are
+// left out of the span as they may carry personal data.
+func traceStatement(ctx context.Context, query string, args []driver.NamedValue) func(err error) {
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	operation = strings.ToUpper(operation)
+
+	_, span := tracing.Start(ctx, "sql "+operation, tracing.WithKind(tracing.KindClient))
+	span.SetAttribute("db.system", systemFromContext(ctx))
+	span.SetAttribute("db.operation", operation)
+	span.SetAttribute("db.statement", strings.Join(strings.Fields(query), " "))
+
+	return func(err error) {
+		if errors.Is(err, driver.ErrSkip) {
+			return
+		}
+
+		span.RecordError(err)
+		span.End()
+	}
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Tx is a unit of work: a transaction, or a savepoint within the
+// transaction of an enclosing unit of work. Statements run on the embedded
+// *sql.Tx either way.
+type Tx struct {
+	*sql.Tx
+	depth int
+}
+
+// TxFromContext returns the unit of work ctx was handed by WithTx, if any.
+func TxFromContext(ctx context.Context) (*Tx, bool) {
+	tx, ok := ctx.Value(txContextKey).(*Tx)
+
+	return tx, ok
+}
+
+// WithTx runs fn as a unit of work, handing it the Tx both directly and
+// through its ctx so repositories can take either. The work is committed
+// when fn returns nil and rolled back when it returns an error or panics.
+//
+// When ctx already carries a unit of work, fn runs within a savepoint of its
+// transaction instead: failing rolls back only what fn did, leaving the
+// enclosing unit to carry on or fail as a whole, and succeeding leaves the
+// commit to it.
+func (db *DB) WithTx(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) error {
+	MarkWritten(ctx)
+
+	if outer, ok := TxFromContext(ctx); ok {
+		return outer.savepoint(ctx, fn)
+	}
+
+	sqlTx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("could not begin a transaction: %w", err)
+	}
+
+	tx := &Tx{Tx: sqlTx}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(context.WithValue(ctx, txContextKey, tx), tx)
+	if err != nil {
+		tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// savepoint runs fn as a unit of work nested in tx.
+func (tx *Tx) savepoint(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) error {
+	nested := &Tx{Tx: tx.Tx, depth: tx.depth + 1}
+	name := fmt.Sprintf("unit_of_work_%d", nested.depth)
+
+	_, err := tx.ExecContext(ctx, "SAVEPOINT "+name)
+	if err != nil {
+		return fmt.Errorf("could not create savepoint %s: %w", name, err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+
+	err = fn(context.WithValue(ctx, txContextKey, nested), nested)
+	if err != nil {
+		_, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		if rollbackErr != nil {
+			return fmt.Errorf("%w; rolling back to savepoint %s: %v", err, name, rollbackErr)
+		}
+
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	if err != nil {
+		return fmt.Errorf("could not release savepoint %s: %w", name, err)
+	}
+
+	return nil
+}
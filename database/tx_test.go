@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mmiftahrzki/customer/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, config.DatabaseConfig{
+		Driver:          DriverSQLite,
+		Name:            filepath.Join(t.TempDir(), "test.db"),
+		MaxConnection:   2,
+		ConnectAttempts: 1,
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, "CREATE TABLE t (name TEXT NOT NULL)")
+	require.NoError(t, err)
+
+	insert := func(name string) func(ctx context.Context, tx *Tx) error {
+		return func(ctx context.Context, tx *Tx) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO t (name) VALUES (?)", name)
+
+			return err
+		}
+	}
+
+	names := func() []string {
+		rows, err := db.QueryContext(ctx, "SELECT name FROM t ORDER BY name")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		names := []string{}
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+
+		return names
+	}
+
+	failure := errors.New("failure")
+
+	require.NoError(t, db.WithTx(ctx, insert("committed")))
+
+	err = db.WithTx(ctx, func(ctx context.Context, tx *Tx) error {
+		require.NoError(t, insert("rolled back")(ctx, tx))
+
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	assert.Panics(t, func() {
+		db.WithTx(ctx, func(ctx context.Context, tx *Tx) error {
+			require.NoError(t, insert("panicked")(ctx, tx))
+
+			panic("boom")
+		})
+	})
+
+	err = db.WithTx(ctx, func(ctx context.Context, tx *Tx) error {
+		require.NoError(t, insert("outer")(ctx, tx))
+
+		inner, ok := TxFromContext(ctx)
+		require.True(t, ok)
+		assert.Same(t, tx, inner)
+
+		err := db.WithTx(ctx, func(ctx context.Context, tx *Tx) error {
+			require.NoError(t, insert("savepoint rolled back")(ctx, tx))
+
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+
+		return db.WithTx(ctx, insert("savepoint released"))
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"committed", "outer", "savepoint released"}, names())
+}
@@ -0,0 +1,192 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mmiftahrzki/customer/config"
+)
+
+const prefix string = "enc:v1:"
+
+var errMalformedValue = errors.New("encryption: malformed encrypted value")
+var errUnknownKey = errors.New("encryption: value was encrypted with a key missing from the keyring")
+
+// Fields encrypts the configured columns with envelope encryption: every
+// value gets its own random data key, sealed with AES-GCM, and that data key
+// is in turn sealed with the keyring's active key. Columns that are not
+// configured, and a nil *Fields, pass values through untouched.
+type Fields struct {
+	keyring *Keyring
+	columns map[string]bool
+}
+
+// New enables encryption for columns, named as "table.column".
+func New(keyring *Keyring, columns []string) *Fields {
+	fields := &Fields{keyring: keyring, columns: map[string]bool{}}
+
+	for _, column := range columns {
+		fields.columns[column] = true
+	}
+
+	return fields
+}
+
+// Enabled reports whether values of column are encrypted.
+func (f *Fields) Enabled(column string) bool {
+	return f != nil && f.columns[column]
+}
+
+// Encrypt seals value when column is configured for encryption.
+func (f *Fields) Encrypt(column string, value string) (string, error) {
+	if !f.Enabled(column) {
+		return value, nil
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := seal(f.keyring.keys[f.keyring.active], dataKey, []byte(column))
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := seal(dataKey, []byte(value), []byte(column))
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + f.keyring.active + ":" +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt. Values without the encryption
+// prefix are returned as they are, so plaintext rows written before a column
+// was configured stay readable until they are rotated.
+func (f *Fields) Decrypt(column string, value string) (string, error) {
+	if f == nil || !strings.HasPrefix(value, prefix) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", errMalformedValue
+	}
+
+	keyEncryptionKey, ok := f.keyring.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", errUnknownKey, parts[0])
+	}
+
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errMalformedValue
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errMalformedValue
+	}
+
+	dataKey, err := open(keyEncryptionKey, wrappedKey, []byte(column))
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(dataKey, sealed, []byte(column))
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether value of column is stored in plaintext or
+// under a key other than the active one.
+func (f *Fields) NeedsRotation(column string, value string) bool {
+	if !f.Enabled(column) || value == "" {
+		return false
+	}
+
+	return !strings.HasPrefix(value, prefix+f.keyring.active+":")
+}
+
+// BlindIndex returns a deterministic HMAC of the normalized value so equality
+// lookups and unique keys keep working on an encrypted column. It returns an
+// empty string when column is not encrypted.
+func (f *Fields) BlindIndex(column string, value string) string {
+	if !f.Enabled(column) {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, f.keyring.indexKey)
+	mac.Write([]byte(column))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key []byte, sealed []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, errMalformedValue
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Load builds the Fields described by cfg. It returns a nil *Fields, which
+// leaves every column in plaintext, when no keyring file is configured.
+func Load(cfg config.EncryptionConfig) (*Fields, error) {
+	if cfg.KeyringFile == "" {
+		return nil, nil
+	}
+
+	keyring, err := LoadKeyring(cfg.KeyringFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(keyring, cfg.Columns), nil
+}
@@ -0,0 +1,99 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeKeyring(t *testing.T, active string, ids ...string) *Keyring {
+	keys := ""
+	for i, id := range ids {
+		if i > 0 {
+			keys += ","
+		}
+
+		keys += fmt.Sprintf("%q: %q", id, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{byte(i + 1)}, keySize)))
+	}
+
+	indexKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, keySize))
+	content := fmt.Sprintf(`{"active": %q, "keys": {%s}, "index_key": %q}`, active, keys, indexKey)
+
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keyring, err := LoadKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keyring
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	fields := New(writeKeyring(t, "k1", "k1"), []string{"customer.email"})
+
+	encrypted, err := fields.Encrypt("customer.email", "mary.smith@example.org")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.NotContains(t, encrypted, "mary")
+
+	decrypted, err := fields.Decrypt("customer.email", encrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, "mary.smith@example.org", decrypted)
+
+	_, err = fields.Decrypt("customer.first_name", encrypted)
+	assert.NotNil(t, err, "a value must not decrypt under another column")
+}
+
+func TestUnconfiguredColumnPassesThrough(t *testing.T) {
+	fields := New(writeKeyring(t, "k1", "k1"), []string{"customer.email"})
+
+	value, err := fields.Encrypt("customer.first_name", "MARY")
+	assert.Nil(t, err)
+	assert.Equal(t, "MARY", value)
+
+	var disabled *Fields
+	value, err = disabled.Encrypt("customer.email", "mary@example.org")
+	assert.Nil(t, err)
+	assert.Equal(t, "mary@example.org", value)
+	assert.Equal(t, "", disabled.BlindIndex("customer.email", "mary@example.org"))
+}
+
+func TestRotation(t *testing.T) {
+	old := New(writeKeyring(t, "k1", "k1"), []string{"customer.email"})
+	rotated := New(writeKeyring(t, "k2", "k1", "k2"), []string{"customer.email"})
+
+	encrypted, err := old.Encrypt("customer.email", "mary@example.org")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.False(t, old.NeedsRotation("customer.email", encrypted))
+	assert.True(t, rotated.NeedsRotation("customer.email", encrypted))
+	assert.True(t, rotated.NeedsRotation("customer.email", "mary@example.org"))
+
+	decrypted, err := rotated.Decrypt("customer.email", encrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, "mary@example.org", decrypted)
+}
+
+func TestBlindIndex(t *testing.T) {
+	fields := New(writeKeyring(t, "k1", "k1"), []string{"customer.email"})
+
+	a := fields.BlindIndex("customer.email", "Mary@Example.org ")
+	b := fields.BlindIndex("customer.email", "mary@example.org")
+
+	assert.Equal(t, a, b)
+	assert.Len(t, a, 64)
+	assert.NotEqual(t, a, fields.BlindIndex("customer.email", "john@example.org"))
+}
@@ -0,0 +1,92 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const keySize int = 32
+
+var errKeyringNoActiveKey = errors.New("encryption: keyring has no active key")
+var errKeyringNoIndexKey = errors.New("encryption: keyring has no index key")
+
+// Keyring holds the key encryption keys by id and the key used for blind
+// indexes. Only the active key encrypts; every key can still decrypt so rows
+// written before a rotation stay readable.
+type Keyring struct {
+	active   string
+	keys     map[string][]byte
+	indexKey []byte
+}
+
+type keyringFile struct {
+	Active   string            `json:"active"`
+	Keys     map[string]string `json:"keys"`
+	IndexKey string            `json:"index_key"`
+}
+
+// LoadKeyring reads a keyring file of the form
+//
+//	{"active": "2025-01", "keys": {"2025-01": "<base64>"}, "index_key": "<base64>"}
+//
+// where every key is 32 random bytes encoded as standard base64.
+func LoadKeyring(path string) (*Keyring, error) {
+	var file keyringFile
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: failed to read keyring: %w", err)
+	}
+
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: failed to parse keyring: %w", err)
+	}
+
+	keyring := &Keyring{active: file.Active, keys: map[string][]byte{}}
+
+	for id, encoded := range file.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("encryption: key id %q must be non-empty and cannot contain ':'", id)
+		}
+
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption: key %q: %w", id, err)
+		}
+
+		keyring.keys[id] = key
+	}
+
+	if _, ok := keyring.keys[keyring.active]; !ok {
+		return nil, errKeyringNoActiveKey
+	}
+
+	if file.IndexKey == "" {
+		return nil, errKeyringNoIndexKey
+	}
+
+	keyring.indexKey, err = decodeKey(file.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: index key: %w", err)
+	}
+
+	return keyring, nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(key))
+	}
+
+	return key, nil
+}
@@ -0,0 +1,33 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/mmiftahrzki/customer/responses"
+)
+
+type handler struct {
+	registry *registry
+}
+
+func newHandler(registry *registry) handler {
+	return handler{registry: registry}
+}
+
+// Liveness only tells that the process is up and serving HTTP.
+func (h handler) Liveness(w http.ResponseWriter, r *http.Request) {
+	responses.WithJson(w, http.StatusOK, report{Status: statusOK, Checks: map[string]checkResult{}})
+}
+
+// Readiness runs every registered check and answers 503 when any of them
+// fails.
+func (h handler) Readiness(w http.ResponseWriter, r *http.Request) {
+	result := h.registry.run(r.Context())
+
+	code := http.StatusOK
+	if result.Status != statusOK {
+		code = http.StatusServiceUnavailable
+	}
+
+	responses.WithJson(w, code, result)
+}
@@ -0,0 +1,132 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultTimeout time.Duration = 2 * time.Second
+
+var errDraining = errors.New("server is shutting down")
+
+// Check reports whether a dependency is usable. It must return once ctx is
+// done.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type registry struct {
+	mu       sync.RWMutex
+	checks   []namedCheck
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+// Health is a registry of readiness checks together with the handlers
+// serving them.
+type Health struct {
+	Handler  handler
+	registry *registry
+}
+
+// New returns an empty checker registry. Each check gets timeout to finish
+// before it is reported as failed; zero means two seconds.
+func New(timeout time.Duration) Health {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	registry := &registry{timeout: timeout}
+
+	return Health{
+		Handler:  newHandler(registry),
+		registry: registry,
+	}
+}
+
+// Register adds a readiness check under name.
+func (h Health) Register(name string, check Check) {
+	h.registry.mu.Lock()
+	defer h.registry.mu.Unlock()
+
+	h.registry.checks = append(h.registry.checks, namedCheck{name, check})
+}
+
+// Drain makes readiness fail from now on so load balancers stop routing new
+// requests while in-flight ones finish.
+func (h Health) Drain() {
+	h.registry.draining.Store(true)
+}
+
+type checkResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+type report struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+const (
+	statusOK   string = "ok"
+	statusFail string = "fail"
+)
+
+// run executes every check concurrently and reports ok only when all of them
+// pass and the registry is not draining.
+func (r *registry) run(ctx context.Context) report {
+	r.mu.RLock()
+	checks := append([]namedCheck(nil), r.checks...)
+	r.mu.RUnlock()
+
+	result := report{Status: statusOK, Checks: make(map[string]checkResult, len(checks)+1)}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, c := range checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c.check(checkCtx)
+			current := checkResult{Status: statusOK, DurationMs: float64(time.Since(start).Microseconds()) / 1000}
+
+			if err != nil {
+				current.Status = statusFail
+				current.Error = err.Error()
+			}
+
+			mu.Lock()
+			result.Checks[c.name] = current
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if r.draining.Load() {
+		result.Checks["shutdown"] = checkResult{Status: statusFail, Error: errDraining.Error()}
+	}
+
+	for _, current := range result.Checks {
+		if current.Status != statusOK {
+			result.Status = statusFail
+		}
+	}
+
+	return result
+}
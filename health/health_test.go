@@ -0,0 +1,56 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadiness(t *testing.T) {
+	h := New(50 * time.Millisecond)
+	h.Register("ok", func(ctx context.Context) error { return nil })
+
+	recorder := httptest.NewRecorder()
+	h.Handler.Readiness(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	h.Register("broken", func(ctx context.Context) error { return errors.New("broken") })
+
+	recorder = httptest.NewRecorder()
+	h.Handler.Readiness(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"broken":{"status":"fail","error":"broken"`)
+}
+
+func TestReadinessTimeout(t *testing.T) {
+	h := New(10 * time.Millisecond)
+	h.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	})
+
+	result := h.registry.run(context.Background())
+
+	assert.Equal(t, statusFail, result.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), result.Checks["slow"].Error)
+}
+
+func TestDrain(t *testing.T) {
+	h := New(0)
+	h.Register("ok", func(ctx context.Context) error { return nil })
+	h.Drain()
+
+	recorder := httptest.NewRecorder()
+	h.Handler.Readiness(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	h.Handler.Liveness(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mmiftahrzki/customer/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	FormatJSON   string = "json"
+	FormatLogfmt string = "logfmt"
+	FormatText   string = "text"
+)
+
+var closeOutputs func() error = func() error { return nil }
+
+func newFormatter(format string) (logrus.Formatter, error) {
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+		return &logrus.JSONFormatter{TimestampFormat: time.RFC3339}, nil
+	case FormatLogfmt:
+		return &logrus.TextFormatter{DisableColors: true, FullTimestamp: true, TimestampFormat: time.RFC3339}, nil
+	case FormatText:
+		return &logrus.TextFormatter{FullTimestamp: true, TimestampFormat: time.RFC3339}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+func newOutput(cfg config.LogOutputConfig) (io.Writer, error) {
+	switch cfg.Path {
+	case "", "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	default:
+		return newRotatingFile(cfg.Path, cfg.MaxSizeMB, cfg.RotateInterval, cfg.MaxBackups, cfg.MaxAge)
+	}
+}
+
+// Configure applies cfg to the root logger. Outputs opened by a previous
+// call are closed once the new ones are in place.
+func Configure(cfg config.LoggingConfig) error {
+	level := logrus.InfoLevel
+	if cfg.Level != "" {
+		parsed, err := logrus.ParseLevel(cfg.Level)
+		if err != nil {
+			return err
+		}
+
+		level = parsed
+	}
+
+	formatter, err := newFormatter(cfg.Format)
+	if err != nil {
+		return err
+	}
+
+	writers := []io.Writer{}
+	closers := []io.Closer{}
+
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []config.LogOutputConfig{{Path: "stdout"}}
+	}
+
+	for _, output := range outputs {
+		writer, err := newOutput(output)
+		if err != nil {
+			for _, closer := range closers {
+				closer.Close()
+			}
+
+			return fmt.Errorf("failed to open log output %s: %w", output.Path, err)
+		}
+
+		writers = append(writers, writer)
+		if closer, ok := writer.(*rotatingFile); ok {
+			closers = append(closers, closer)
+		}
+	}
+
+	previous := closeOutputs
+
+	Logger.SetFormatter(formatter)
+	Logger.SetOutput(io.MultiWriter(writers...))
+	Logger.SetLevel(level)
+
+	closeOutputs = func() error {
+		errs := []error{}
+		for _, closer := range closers {
+			errs = append(errs, closer.Close())
+		}
+
+		return errors.Join(errs...)
+	}
+
+	return previous()
+}
+
+// Close closes the log files opened by Configure.
+func Close() error {
+	return closeOutputs()
+}
+
+// SetLevel changes the level of the root logger at runtime.
+func SetLevel(level string) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	Logger.SetLevel(parsed)
+
+	return nil
+}
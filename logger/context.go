@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+type contextKey struct{}
+
+// scope holds the request-scoped logger. It is shared by every context
+// derived from the one NewContext returned, so fields added deep in the
+// call chain, such as the authenticated user, reach the access log too.
+type scope struct {
+	mu    sync.Mutex
+	entry *logrus.Entry
+}
+
+// NewContext returns a copy of ctx carrying entry as its request-scoped
+// logger.
+func NewContext(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, contextKey{}, &scope{entry: entry})
+}
+
+// FromContext returns the request-scoped logger of ctx, or the root logger
+// when ctx has none. The entry carries ctx so hooks can read from it.
+func FromContext(ctx context.Context) *logrus.Entry {
+	s, ok := ctx.Value(contextKey{}).(*scope)
+	if !ok {
+		return logrus.NewEntry(Logger).WithContext(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entry.WithContext(ctx)
+}
+
+// AddFields adds fields to the request-scoped logger of ctx, if it has one.
+func AddFields(ctx context.Context, fields logrus.Fields) {
+	s, ok := ctx.Value(contextKey{}).(*scope)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entry = s.entry.WithFields(fields)
+}
+
+// WithContext returns entry, usually a component logger, enriched with the
+// fields of the request-scoped logger of ctx.
+func WithContext(ctx context.Context, entry *logrus.Entry) *logrus.Entry {
+	return entry.WithFields(FromContext(ctx).Data).WithContext(ctx)
+}
@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mmiftahrzki/customer/requests"
+	"github.com/mmiftahrzki/customer/responses"
+)
+
+const maxBodyBytes int64 = 1 << 10
+
+type modelLevel struct {
+	Level string `json:"level"`
+}
+
+type handler struct{}
+
+// NewHandler returns the handlers of the admin endpoint reading and changing
+// the log level at runtime.
+func NewHandler() handler {
+	return handler{}
+}
+
+func (h handler) GetLevel(w http.ResponseWriter, r *http.Request) {
+	var res responses.GetSingleResponse[modelLevel]
+
+	res.Data = modelLevel{Level: Logger.GetLevel().String()}
+
+	responses.WithJson(w, http.StatusOK, res)
+}
+
+func (h handler) PutLevel(w http.ResponseWriter, r *http.Request) {
+	var res responses.GetSingleResponse[modelLevel]
+
+	defer r.Body.Close()
+
+	var decodeErr *requests.DecodeError
+
+	payload := modelLevel{}
+	err := requests.DecodeJson(w, r, &payload, maxBodyBytes)
+	if errors.As(err, &decodeErr) {
+		responses.Error(w, r, decodeErr.Status, decodeErr.Message)
+
+		return
+	}
+
+	previous := Logger.GetLevel().String()
+
+	err = SetLevel(payload.Level)
+	if err != nil {
+		responses.Error(w, r, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	FromContext(r.Context()).WithField("component", "logger").Infof("log level changed from %s to %s", previous, payload.Level)
+
+	res.Data = modelLevel{Level: Logger.GetLevel().String()}
+
+	responses.WithJson(w, http.StatusOK, res)
+}
@@ -19,6 +19,7 @@ func init() {
 
 	Logger.SetOutput(os.Stdout)
 	Logger.SetLevel(logrus.DebugLevel)
+	Logger.AddHook(redactHook{})
 }
 
 func GetLogger() *logrus.Logger {
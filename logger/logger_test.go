@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactHook(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer abc.def.ghi")
+	header.Set("Accept", "application/json")
+
+	entry := logrus.NewEntry(logrus.New()).WithFields(logrus.Fields{
+		"user":          "mary.smith@example.org",
+		"error":         errors.New("customer mary@example.org already exists"),
+		"authorization": "Basic dXNlcjpwYXNz",
+		"header":        header,
+		"status":        200,
+	})
+	entry.Message = "token eyJhbGciOiJIUzI1NiJ9.eyJlbWFpbCI6Im1AZS5vcmcifQ.sig sent with Bearer abc123"
+
+	assert.Nil(t, redactHook{}.Fire(entry))
+
+	assert.Equal(t, "token [REDACTED] sent with Bearer [REDACTED]", entry.Message)
+	assert.Equal(t, "m***@example.org", entry.Data["user"])
+	assert.Equal(t, "customer m***@example.org already exists", entry.Data["error"])
+	assert.Equal(t, redacted, entry.Data["authorization"])
+	assert.Equal(t, redacted, entry.Data["header"].(http.Header).Get("Authorization"))
+	assert.Equal(t, "Bearer abc.def.ghi", header.Get("Authorization"), "the logged header must not be modified")
+	assert.Equal(t, 200, entry.Data["status"])
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rf, err := newRotatingFile(path, 1, time.Hour, 2, 0)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer rf.Close()
+
+	rf.now = func() time.Time { return now }
+	rf.openedAt = now
+
+	line := make([]byte, 400<<10)
+
+	for i := 0; i < 3; i++ {
+		_, err = rf.Write(line)
+		assert.Nil(t, err)
+	}
+
+	backups, _ := filepath.Glob(path + ".*")
+	assert.Len(t, backups, 1, "the third write crosses 1MB")
+
+	now = now.Add(time.Hour)
+	_, err = rf.Write([]byte("x"))
+	assert.Nil(t, err)
+
+	now = now.Add(time.Hour)
+	_, err = rf.Write([]byte("y"))
+	assert.Nil(t, err)
+
+	backups, _ = filepath.Glob(path + ".*")
+	assert.Len(t, backups, 2, "only maxBackups rotated files are kept")
+
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "y", string(content))
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted string = "[REDACTED]"
+
+var emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+var jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+var bearerPattern = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`)
+
+// sensitiveKeys are field names whose whole value is masked.
+var sensitiveKeys = []string{"authorization", "password", "secret", "token", "api_key", "apikey", "cookie"}
+
+// redactHook masks personal data and credentials in the message and fields
+// of every entry before it is formatted: emails keep their first character
+// and domain, tokens and Authorization headers are replaced entirely.
+type redactHook struct{}
+
+func (redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = redactString(entry.Message)
+
+	for key, value := range entry.Data {
+		entry.Data[key] = redactField(key, value)
+	}
+
+	return nil
+}
+
+func redactString(value string) string {
+	value = jwtPattern.ReplaceAllString(value, redacted)
+	value = bearerPattern.ReplaceAllString(value, "$1 "+redacted)
+
+	return emailPattern.ReplaceAllString(value, "$1***@$2")
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func redactField(key string, value any) any {
+	if sensitiveKey(key) {
+		return redacted
+	}
+
+	switch v := value.(type) {
+	case string:
+		return redactString(v)
+	case error:
+		return redactString(v.Error())
+	case http.Header:
+		header := v.Clone()
+		for name := range header {
+			if sensitiveKey(name) {
+				header[name] = []string{redacted}
+			} else {
+				for i, s := range header[name] {
+					header[name][i] = redactString(s)
+				}
+			}
+		}
+
+		return header
+	default:
+		return value
+	}
+}
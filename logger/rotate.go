@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat string = "20060102T150405.000000000"
+
+// rotatingFile is a log file renamed aside, with a timestamp suffix, once it
+// reaches maxSize bytes or has been open for interval.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	maxAge     time.Duration
+	file       *os.File
+	size       int64
+	openedAt   time.Time
+	now        func() time.Time
+}
+
+func newRotatingFile(path string, maxSizeMB int, interval time.Duration, maxBackups int, maxAge time.Duration) (*rotatingFile, error) {
+	rf := &rotatingFile{
+		path:       path,
+		maxSize:    int64(maxSizeMB) << 20,
+		interval:   interval,
+		maxBackups: maxBackups,
+		maxAge:     maxAge,
+		now:        time.Now,
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	rf.file = file
+	rf.size = info.Size()
+	rf.openedAt = rf.now()
+
+	return nil
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	tooBig := rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize
+	tooOld := rf.interval > 0 && rf.now().Sub(rf.openedAt) >= rf.interval
+
+	if tooBig || tooOld {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+
+	return n, err
+}
+
+func (rf *rotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+
+	backup := fmt.Sprintf("%s.%s", rf.path, rf.now().UTC().Format(backupTimeFormat))
+	if err := os.Rename(rf.path, backup); err != nil {
+		return err
+	}
+
+	if err := rf.open(); err != nil {
+		return err
+	}
+
+	return rf.prune()
+}
+
+// prune removes the rotated files beyond maxBackups and those older than
+// maxAge, judged by the timestamp in their name.
+func (rf *rotatingFile) prune() error {
+	matches, err := filepath.Glob(rf.path + ".*")
+	if err != nil {
+		return err
+	}
+
+	type backup struct {
+		path      string
+		rotatedAt time.Time
+	}
+	backups := []backup{}
+
+	for _, match := range matches {
+		rotatedAt, err := time.Parse(backupTimeFormat, strings.TrimPrefix(match, rf.path+"."))
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, backup{path: match, rotatedAt: rotatedAt})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].rotatedAt.After(backups[j].rotatedAt)
+	})
+
+	for i, b := range backups {
+		expired := rf.maxAge > 0 && rf.now().Sub(b.rotatedAt) > rf.maxAge
+		surplus := rf.maxBackups > 0 && i >= rf.maxBackups
+
+		if expired || surplus {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	return rf.file.Close()
+}
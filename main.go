@@ -1,31 +1,149 @@
 package main
 
 import (
+	"context"
 	_ "embed"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/mmiftahrzki/customer/app"
 	"github.com/mmiftahrzki/customer/config"
+	"github.com/mmiftahrzki/customer/customer"
 	"github.com/mmiftahrzki/customer/database"
+	"github.com/mmiftahrzki/customer/encryption"
 	"github.com/mmiftahrzki/customer/logger"
+	"github.com/mmiftahrzki/customer/tracing"
+	"github.com/mmiftahrzki/customer/user"
+)
+
+const (
+	exitOK     int = 0
+	exitError  int = 1
+	exitForced int = 2
 )
 
 func main() {
-	logger := logger.GetLogger()
+	os.Exit(run())
+}
+
+// run owns every resource so deferred cleanups, closing the database last,
+// happen before the process exits with the returned code.
+func run() int {
+	log := logger.GetLogger()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	args := os.Args[1:]
+	rotateKeys := len(args) > 0 && args[0] == "rotate-keys"
+	if rotateKeys {
+		args = args[1:]
+	}
+
+	cfg, err := config.LoadConfig(args)
+	if errors.Is(err, config.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		log.Errorf("Config Error: %v\n", err)
 
-	cfg, err := config.LoadConfig()
+		return exitError
+	}
+
+	user.SetSecretKey([]byte(cfg.Auth.JWTSecretKey))
+
+	err = logger.Configure(cfg.Logging)
+	if err != nil {
+		log.Errorf("Logging Error: %v\n", err)
+
+		return exitError
+	}
+	defer logger.Close()
+
+	shutdownTracing, err := tracing.Setup(cfg.Tracing)
 	if err != nil {
-		logger.Fatalln(err)
+		log.Errorf("Tracing Error: %v\n", err)
+
+		return exitError
 	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Errorf("Tracing Error: %v\n", err)
+		}
+	}()
 
-	db, err := database.New(cfg.Database)
+	log.AddHook(tracing.LogHook{})
+
+	db, err := database.New(ctx, cfg.Database)
 	if err != nil {
-		logger.Fatalf("Database Error: %v\n", err)
+		log.Errorf("Database Error: %v\n", err)
+
+		return exitError
 	}
-	defer db.Close()
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Errorf("Database Error: %v\n", err)
+		}
+	}()
+
+	err = database.Migrate(ctx, db)
+	if err != nil {
+		log.Errorf("Migration Error: %v\n", err)
+
+		return exitError
+	}
+
+	fields, err := encryption.Load(cfg.Encryption)
+	if err != nil {
+		log.Errorf("Encryption Error: %v\n", err)
+
+		return exitError
+	}
+
+	if rotateKeys {
+		err = customer.RotateKeys(ctx, db, fields, 500)
+		if err != nil {
+			log.Errorf("Key Rotation Error: %v\n", err)
+
+			return exitError
+		}
+
+		return exitOK
+	}
+
+	config.Subscribe(func(previous config.Config, next config.Config) {
+		if next.Logging.Level != previous.Logging.Level {
+			if err := logger.SetLevel(next.Logging.Level); err != nil {
+				log.Error(err)
+			}
+		}
+	})
+
+	server := app.New(cfg, db, fields)
+	server.Go(func(ctx context.Context) {
+		config.Watch(ctx, log.WithField("component", "config"))
+	})
+
+	err = server.Run(ctx)
+	if err != nil {
+		log.Error(err)
+	}
+
+	return exitCode(err)
+}
 
-	app := app.New(cfg.App, db)
-	if err = app.Run(); err != nil {
-		logger.Panic(err)
+// exitCode is the code the process exits with once the server stopped with
+// err: exitForced when in-flight work had to be dropped.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, app.ErrForcedShutdown):
+		return exitForced
+	default:
+		return exitError
 	}
 }
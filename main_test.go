@@ -0,0 +1,17 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mmiftahrzki/customer/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, exitOK, exitCode(nil))
+	assert.Equal(t, exitForced, exitCode(app.ErrForcedShutdown))
+	assert.Equal(t, exitForced, exitCode(fmt.Errorf("shutdown: %w", app.ErrForcedShutdown)))
+	assert.Equal(t, exitError, exitCode(errors.New("listen tcp :8080: bind: address already in use")))
+}
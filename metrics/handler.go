@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+)
+
+type handler struct {
+	registry *Registry
+}
+
+// Handler serves the registry in the Prometheus text exposition format.
+func Handler(registry *Registry) http.Handler {
+	return handler{registry: registry}
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	buffer := bytes.NewBuffer(nil)
+	h.registry.Write(buffer)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buffer.Bytes())
+}
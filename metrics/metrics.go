@@ -0,0 +1,259 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, used for latency
+// histograms.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var registry = NewRegistry()
+
+// GetRegistry returns the process wide registry served on /metrics.
+func GetRegistry() *Registry {
+	return registry
+}
+
+type metric interface {
+	write(w io.Writer, name string)
+	kind() string
+}
+
+// Registry keeps metrics by name and renders them in the Prometheus text
+// exposition format.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+	help    map[string]string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{metrics: map[string]metric{}, help: map[string]string{}}
+}
+
+// register stores m under name, or returns the metric already registered
+// under that name so packages constructed more than once share a series.
+func (r *Registry) register(name, help string, m metric) metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.metrics[name]; ok {
+		if existing.kind() != m.kind() {
+			panic(fmt.Sprintf("metrics: %s already registered as a %s", name, existing.kind()))
+		}
+
+		return existing
+	}
+
+	r.metrics[name] = m
+	r.help[name] = help
+
+	return m
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return r.register(name, help, &CounterVec{labels: labels, values: map[string]*series{}}).(*CounterVec)
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return r.register(name, help, &HistogramVec{labels: labels, buckets: buckets, values: map[string]*histogramSeries{}}).(*HistogramVec)
+}
+
+// NewGaugeFunc exposes the value returned by fn at scrape time.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, help, &funcMetric{typ: "gauge", fn: fn})
+}
+
+// NewCounterFunc exposes a monotonically increasing value returned by fn at
+// scrape time.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(name, help, &funcMetric{typ: "counter", fn: fn})
+}
+
+// Write renders every metric, sorted by name.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	r.mu.Unlock()
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		r.mu.Lock()
+		m, help := r.metrics[name], r.help[name]
+		r.mu.Unlock()
+
+		fmt.Fprintf(w, "# HELP %s %s\n", name, strings.ReplaceAll(help, "\n", " "))
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, m.kind())
+		m.write(w, name)
+	}
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+// CounterVec is a counter partitioned by label values.
+type CounterVec struct {
+	mu     sync.Mutex
+	labels []string
+	values map[string]*series
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.values[key]
+	if !ok {
+		s = &series{labelValues: labelValues}
+		c.values[key] = s
+	}
+
+	s.value += delta
+}
+
+func (c *CounterVec) kind() string {
+	return "counter"
+}
+
+func (c *CounterVec) write(w io.Writer, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range sortedKeys(c.values) {
+		s := c.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(c.labels, s.labelValues), formatValue(s.value))
+	}
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+// HistogramVec is a histogram partitioned by label values.
+type HistogramVec struct {
+	mu      sync.Mutex
+	labels  []string
+	buckets []float64
+	values  map[string]*histogramSeries
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.values[key]
+	if !ok {
+		s = &histogramSeries{labelValues: labelValues, counts: make([]uint64, len(h.buckets))}
+		h.values[key] = s
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+
+	s.count++
+	s.sum += value
+}
+
+func (h *HistogramVec) kind() string {
+	return "histogram"
+}
+
+func (h *HistogramVec) write(w io.Writer, name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	labels := append(append([]string{}, h.labels...), "le")
+
+	for _, key := range sortedKeys(h.values) {
+		s := h.values[key]
+
+		for i, bound := range h.buckets {
+			values := append(append([]string{}, s.labelValues...), formatValue(bound))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(labels, values), s.counts[i])
+		}
+
+		values := append(append([]string{}, s.labelValues...), "+Inf")
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(labels, values), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, formatLabels(h.labels, s.labelValues), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, formatLabels(h.labels, s.labelValues), s.count)
+	}
+}
+
+type funcMetric struct {
+	typ string
+	fn  func() float64
+}
+
+func (f *funcMetric) kind() string {
+	return f.typ
+}
+
+func (f *funcMetric) write(w io.Writer, name string) {
+	fmt.Fprintf(w, "%s %s\n", name, formatValue(f.fn()))
+}
+
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names []string, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(value))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
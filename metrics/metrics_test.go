@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExposition(t *testing.T) {
+	registry := NewRegistry()
+
+	requests := registry.NewCounterVec("http_requests_total", "Requests served.", "method", "route")
+	requests.Inc("GET", "/api/customer/{id}")
+	requests.Inc("GET", "/api/customer/{id}")
+	requests.Add(3, "POST", `say "hi"`)
+
+	latency := registry.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	latency.Observe(0.05, "/a")
+	latency.Observe(0.5, "/a")
+	latency.Observe(5, "/a")
+
+	registry.NewGaugeFunc("pool_open", "Open connections.", func() float64 { return 4 })
+
+	buffer := bytes.NewBuffer(nil)
+	registry.Write(buffer)
+
+	expected := `# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="/api/customer/{id}"} 2
+http_requests_total{method="POST",route="say \"hi\""} 3
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 1
+latency_seconds_bucket{route="/a",le="1"} 2
+latency_seconds_bucket{route="/a",le="+Inf"} 3
+latency_seconds_sum{route="/a"} 5.55
+latency_seconds_count{route="/a"} 3
+# HELP pool_open Open connections.
+# TYPE pool_open gauge
+pool_open 4
+`
+
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestRegisterReturnsExisting(t *testing.T) {
+	registry := NewRegistry()
+
+	a := registry.NewCounterVec("created_total", "Created.")
+	b := registry.NewCounterVec("created_total", "Created.")
+
+	assert.Same(t, a, b)
+	assert.Panics(t, func() { registry.NewHistogramVec("created_total", "Created.", DefaultBuckets) })
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops the buckets that have
+// filled up again, which are no different from absent ones.
+const sweepInterval time.Duration = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// memoryStore keeps the buckets in process, so each instance of the service
+// limits clients on its own.
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+// NewMemoryStore returns a Store keeping its buckets in memory.
+func NewMemoryStore() *memoryStore {
+	return &memoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (s *memoryStore) Take(ctx context.Context, key string, policy Policy) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	capacity := float64(policy.burst())
+	interval := policy.interval()
+
+	if now.Sub(s.swept) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = min(capacity, b.tokens+float64(now.Sub(b.updated))/float64(interval))
+	b.updated = now
+
+	decision := Decision{Limit: policy.burst()}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+
+	decision.Remaining = int(b.tokens)
+	decision.Reset = time.Duration((capacity - b.tokens) * float64(interval))
+	b.full = now.Add(decision.Reset)
+
+	return decision, nil
+}
+
+func (s *memoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.swept = now
+}
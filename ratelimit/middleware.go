@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mmiftahrzki/customer/logger"
+	"github.com/mmiftahrzki/customer/metrics"
+	"github.com/mmiftahrzki/customer/responses"
+	"github.com/sirupsen/logrus"
+)
+
+var rateLimited = metrics.GetRegistry().NewCounterVec("http_rate_limited_total",
+	"Requests refused for exceeding their client's rate limit, by route pattern.", "route")
+
+// policies are the default policy and the policies of single routes, keyed
+// by lower-cased route pattern.
+type policies struct {
+	fallback Policy
+	routes   map[string]Policy
+}
+
+// Limiter refuses the requests of clients that ran out of tokens. Routes
+// with a policy of their own each have a bucket per client; all other routes
+// share the client's default bucket.
+type Limiter struct {
+	store    Store
+	key      func(r *http.Request) string
+	policies atomic.Pointer[policies]
+	log      *logrus.Entry
+}
+
+// New returns a Limiter keeping its buckets in store and telling clients
+// apart by key. It lets every request through until SetPolicies is called.
+func New(store Store, key func(r *http.Request) string) *Limiter {
+	l := &Limiter{
+		store: store,
+		key:   key,
+		log:   logger.GetLogger().WithField("component", "rateLimit"),
+	}
+	l.policies.Store(&policies{})
+
+	return l
+}
+
+// SetPolicies replaces the policies. Route patterns are matched without
+// regard to case, e.g. "POST /api/auth/{$}".
+func (l *Limiter) SetPolicies(fallback Policy, routes map[string]Policy) {
+	current := &policies{fallback: fallback, routes: make(map[string]Policy, len(routes))}
+	for pattern, policy := range routes {
+		current.routes[strings.ToLower(pattern)] = policy
+	}
+
+	l.policies.Store(current)
+}
+
+// Middleware picks the policy by the pattern the mux matched, so it has to
+// wrap the handlers registered on the mux rather than the mux itself.
+func (l *Limiter) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current := l.policies.Load()
+
+		policy, scope := current.fallback, ""
+		if routePolicy, ok := current.routes[strings.ToLower(r.Pattern)]; ok {
+			policy, scope = routePolicy, r.Pattern
+		}
+
+		if policy.unlimited() {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		decision, err := l.store.Take(r.Context(), scope+"|"+l.key(r), policy)
+		if err != nil {
+			logger.WithContext(r.Context(), l.log).WithError(err).Error("rate limit store failed, letting the request through")
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		header := w.Header()
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", policy.Requests, seconds(policy.Period), policy.burst()))
+		header.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(seconds(decision.Reset)))
+
+		if !decision.Allowed {
+			retryAfter := max(seconds(decision.RetryAfter), 1)
+			header.Set("Retry-After", strconv.Itoa(retryAfter))
+			rateLimited.Inc(r.Pattern)
+
+			responses.Error(w, r, http.StatusTooManyRequests, fmt.Sprintf("too many requests, retry in %d seconds", retryAfter))
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// seconds rounds d up to whole seconds, as the headers carry them.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ClientKey tells clients apart by the API key sent in apiKeyHeader or, when
+// there is none, their IP address. API keys are not checked here, so
+// apiKeyHeader should only be set behind a gateway that validates them;
+// empty, it is ignored.
+//
+// The subject of a JWT is deliberately not a key: user tokens are issued for
+// any email without a credential, so a client rotating emails would get a
+// fresh bucket with every token.
+func ClientKey(apiKeyHeader string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if apiKeyHeader != "" {
+			if key := r.Header.Get(apiKeyHeader); key != "" {
+				sum := sha256.Sum256([]byte(key))
+
+				return "key:" + hex.EncodeToString(sum[:16])
+			}
+		}
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+
+		return "ip:" + host
+	}
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Policy grants Requests tokens per Period to each client, up to Burst of
+// them at once. Burst defaults to Requests. Zero Requests means unlimited.
+type Policy struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+func (p Policy) unlimited() bool {
+	return p.Requests <= 0 || p.Period <= 0
+}
+
+func (p Policy) burst() int {
+	if p.Burst > 0 {
+		return p.Burst
+	}
+
+	return p.Requests
+}
+
+// interval is how long the bucket takes to earn one token back.
+func (p Policy) interval() time.Duration {
+	return p.Period / time.Duration(p.Requests)
+}
+
+// Decision is the outcome of taking a token. Remaining is what is left in
+// the bucket, Reset how long until it is full again and RetryAfter, when the
+// request was refused, how long until the next token.
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Store keeps a token bucket per key. Implementations must take tokens
+// atomically, so several instances of the service can share a store.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy) (Decision, error)
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	policy := Policy{Requests: 60, Period: time.Minute, Burst: 2}
+
+	for range 2 {
+		decision, err := store.Take(ctx, "a", policy)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+	}
+
+	decision, err := store.Take(ctx, "a", policy)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed, "burst should be used up")
+	assert.Equal(t, 0, decision.Remaining)
+	assert.Equal(t, time.Second, decision.RetryAfter)
+	assert.Equal(t, 2*time.Second, decision.Reset)
+
+	decision, _ = store.Take(ctx, "b", policy)
+	assert.True(t, decision.Allowed, "keys should have buckets of their own")
+
+	now = now.Add(time.Second)
+	decision, _ = store.Take(ctx, "a", policy)
+	assert.True(t, decision.Allowed, "a token should be earned back after an interval")
+
+	now = now.Add(time.Hour)
+	decision, _ = store.Take(ctx, "a", policy)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, 1, decision.Remaining, "refills should stop at the burst")
+	assert.Len(t, store.buckets, 1, "full buckets should be swept")
+}
+
+func TestMiddleware(t *testing.T) {
+	limiter := New(NewMemoryStore(), func(r *http.Request) string { return r.RemoteAddr })
+	limiter.SetPolicies(Policy{Requests: 1, Period: time.Minute}, map[string]Policy{
+		"POST /login": {Requests: 2, Period: time.Minute},
+		"GET /free":   {},
+	})
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /a", limiter.Middleware(ok))
+	mux.HandleFunc("GET /b", limiter.Middleware(ok))
+	mux.HandleFunc("POST /login", limiter.Middleware(ok))
+	mux.HandleFunc("GET /free", limiter.Middleware(ok))
+
+	serve := func(method string, target string, client string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		r.RemoteAddr = client
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := serve(http.MethodGet, "/a", "1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1;w=60;burst=1", w.Header().Get("RateLimit-Policy"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+
+	w = serve(http.MethodGet, "/b", "1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "routes without a policy should share a bucket")
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "too many requests")
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/a", "2").Code, "clients should have buckets of their own")
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/login", "1").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/login", "1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodPost, "/login", "1").Code)
+
+	for range 3 {
+		w = serve(http.MethodGet, "/free", "1")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-Api-Key", "secret")
+
+	assert.Equal(t, "ip:192.0.2.1", ClientKey("")(r), "API keys should be ignored unless configured")
+
+	key := ClientKey("X-Api-Key")(r)
+	assert.Regexp(t, `^key:[0-9a-f]{32}$`, key)
+	assert.NotContains(t, key, "secret")
+
+	r.Header.Set("Authorization", "Bearer token")
+	r.Header.Del("X-Api-Key")
+	assert.Equal(t, "ip:192.0.2.1", ClientKey("X-Api-Key")(r), "a bearer token should not pick the bucket")
+}
@@ -0,0 +1,176 @@
+package requests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrEmptyBody is wrapped by the DecodeError of a request without a body,
+// so handlers whose body is optional can tell it apart.
+var ErrEmptyBody = errors.New("request body must not be empty")
+
+// DecodeError says why a request body was refused and with which status the
+// handler should answer. Field is the JSON path of the offending field, if
+// one is to blame.
+type DecodeError struct {
+	Status  int
+	Message string
+	Field   string
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	return e.Message
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func badRequest(format string, args ...any) *DecodeError {
+	return &DecodeError{Status: http.StatusBadRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+// DecodeJson decodes the JSON body of r into dst. The body must be sent as
+// application/json, hold at most maxBytes and a single JSON value, and only
+// fields dst has. Errors are always a *DecodeError naming the offending
+// field by its path, e.g. "address.postal_code".
+func DecodeJson(w http.ResponseWriter, r *http.Request, dst any, maxBytes int64) error {
+	body := bufio.NewReader(http.MaxBytesReader(w, r.Body, maxBytes))
+
+	if _, err := body.Peek(1); err != nil {
+		if errors.Is(err, io.EOF) {
+			return &DecodeError{Status: http.StatusBadRequest, Message: ErrEmptyBody.Error(), Err: ErrEmptyBody}
+		}
+
+		return readError(err, maxBytes)
+	}
+
+	if err := checkContentType(r.Header.Get("Content-Type")); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		return decodeError(err, maxBytes)
+	}
+
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return readError(err, maxBytes)
+		}
+
+		return badRequest("request body must contain a single JSON value")
+	}
+
+	return nil
+}
+
+// Unmarshal decodes a JSON value already read, such as one embedded in a
+// larger body, as strictly as DecodeJson does.
+func Unmarshal(data []byte, dst any) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return badRequest("JSON value must not be empty")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		return decodeError(err, int64(len(data)))
+	}
+
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return badRequest("data must contain a single JSON value")
+	}
+
+	return nil
+}
+
+// checkContentType accepts application/json and the types built on it,
+// such as application/merge-patch+json, in UTF-8.
+func checkContentType(contentType string) *DecodeError {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+		if charset, ok := params["charset"]; !ok || strings.EqualFold(charset, "utf-8") {
+			return nil
+		}
+	}
+
+	return &DecodeError{
+		Status:  http.StatusUnsupportedMediaType,
+		Message: fmt.Sprintf("content type %q is not supported, send application/json", contentType),
+	}
+}
+
+func readError(err error, maxBytes int64) *DecodeError {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &DecodeError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("request body must not be larger than %d bytes", maxBytes),
+			Err:     err,
+		}
+	}
+
+	return &DecodeError{Status: http.StatusBadRequest, Message: "request body could not be read", Err: err}
+}
+
+func decodeError(err error, maxBytes int64) *DecodeError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return badRequest("request body contains malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return badRequest("request body contains malformed JSON")
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return badRequest("request body must be a JSON %s", jsonType(typeErr.Type.Kind()))
+		}
+
+		decodeErr := badRequest("field %q must be a JSON %s", typeErr.Field, jsonType(typeErr.Type.Kind()))
+		decodeErr.Field = typeErr.Field
+
+		return decodeErr
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		decodeErr := badRequest("field %s is not allowed", field)
+		decodeErr.Field, _ = strconv.Unquote(field)
+
+		return decodeErr
+	default:
+		return readError(err, maxBytes)
+	}
+}
+
+// jsonType names the JSON type a Go kind is decoded from.
+func jsonType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Pointer, reflect.Interface:
+		return "value"
+	default:
+		return "number"
+	}
+}
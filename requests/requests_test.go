@@ -0,0 +1,106 @@
+package requests
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAddress struct {
+	City string `json:"city"`
+}
+
+type testPayload struct {
+	Name    string      `json:"name"`
+	Address testAddress `json:"address"`
+	Ids     []int       `json:"ids"`
+}
+
+func decode(body string, contentType string) (testPayload, error) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+
+	payload := testPayload{}
+	err := DecodeJson(httptest.NewRecorder(), r, &payload, 64)
+
+	return payload, err
+}
+
+func TestDecodeJson(t *testing.T) {
+	payload, err := decode(`{"name": "Mary", "address": {"city": "Bandung"}, "ids": [1, 2]}`, "application/json; charset=utf-8")
+	require.NoError(t, err)
+	assert.Equal(t, testPayload{Name: "Mary", Address: testAddress{City: "Bandung"}, Ids: []int{1, 2}}, payload)
+
+	_, err = decode(`{"name": "Mary"}`, "application/merge-patch+json")
+	assert.NoError(t, err)
+
+	for name, test := range map[string]struct {
+		body, contentType string
+		status            int
+		message           string
+	}{
+		"empty":             {"", "application/json", http.StatusBadRequest, "request body must not be empty"},
+		"no content type":   {`{}`, "", http.StatusUnsupportedMediaType, `content type "" is not supported`},
+		"form":              {`{}`, "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType, "is not supported"},
+		"latin-1":           {`{}`, "application/json; charset=iso-8859-1", http.StatusUnsupportedMediaType, "is not supported"},
+		"too large":         {`{"name": "` + strings.Repeat("a", 64) + `"}`, "application/json", http.StatusRequestEntityTooLarge, "larger than 64 bytes"},
+		"unknown field":     {`{"nmae": "Mary"}`, "application/json", http.StatusBadRequest, `field "nmae" is not allowed`},
+		"nested type":       {`{"address": {"city": 1}}`, "application/json", http.StatusBadRequest, `field "address.city" must be a JSON string`},
+		"element type":      {`{"ids": [1, "2"]}`, "application/json", http.StatusBadRequest, `field "ids.1" must be a JSON number`},
+		"not an object":     {`[]`, "application/json", http.StatusBadRequest, "request body must be a JSON object"},
+		"syntax":            {`{"name": }`, "application/json", http.StatusBadRequest, "malformed JSON at offset 10"},
+		"truncated":         {`{"name": "Mary"`, "application/json", http.StatusBadRequest, "malformed JSON"},
+		"two values":        {`{} {}`, "application/json", http.StatusBadRequest, "a single JSON value"},
+		"trailing garbage":  {`{} x`, "application/json", http.StatusBadRequest, "a single JSON value"},
+		"too large trailer": {`{}` + strings.Repeat(" ", 64) + `{}`, "application/json", http.StatusRequestEntityTooLarge, "larger than 64 bytes"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := decode(test.body, test.contentType)
+
+			var decodeErr *DecodeError
+			require.True(t, errors.As(err, &decodeErr), "%v", err)
+			assert.Equal(t, test.status, decodeErr.Status)
+			assert.Contains(t, decodeErr.Message, test.message)
+		})
+	}
+
+	_, err = decode("", "")
+	assert.ErrorIs(t, err, ErrEmptyBody, "an empty body needs no content type")
+}
+
+func TestUnmarshal(t *testing.T) {
+	payload := testPayload{}
+	require.NoError(t, Unmarshal([]byte(`{"name": "Mary"}`), &payload))
+	assert.Equal(t, "Mary", payload.Name)
+
+	assert.ErrorContains(t, Unmarshal([]byte(`{"nmae": "Mary"}`), &payload), `field "nmae" is not allowed`)
+	assert.ErrorContains(t, Unmarshal(nil, &payload), "must not be empty")
+	assert.ErrorContains(t, Unmarshal([]byte(`{} {}`), &payload), "a single JSON value")
+}
+
+func TestValidationError(t *testing.T) {
+	errRequired := errors.New("name is required")
+	errTooLong := errors.New("city is too long")
+
+	assert.NoError(t, ValidationError{}.Err())
+
+	err := ValidationError{Invalid("name", errRequired), Invalid("address.city", errTooLong)}.Err()
+	assert.EqualError(t, err, "name is required; city is too long")
+	assert.ErrorIs(t, err, errTooLong)
+
+	fields := FieldErrors(fmt.Errorf("wrapped: %w", err))
+	if assert.Len(t, fields, 2) {
+		assert.Equal(t, "address.city", fields[1].Field)
+	}
+
+	assert.Len(t, FieldErrors(Invalid("name", errRequired)), 1)
+	assert.Empty(t, FieldErrors(errRequired))
+}
@@ -0,0 +1,72 @@
+package requests
+
+import (
+	"errors"
+	"strings"
+)
+
+// FieldError ties a validation error to the field of the request body it is
+// about, named by its JSON path.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Invalid reports err about field.
+func Invalid(field string, err error) *FieldError {
+	return &FieldError{Field: field, Err: err}
+}
+
+// ValidationError gathers every invalid field of a request body. errors.Is
+// matches the error of any of them.
+type ValidationError []*FieldError
+
+func (e ValidationError) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func (e ValidationError) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, fieldErr := range e {
+		errs[i] = fieldErr
+	}
+
+	return errs
+}
+
+// Err returns e, or nil when no field is invalid.
+func (e ValidationError) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// FieldErrors returns the invalid fields err reports, if any.
+func FieldErrors(err error) []*FieldError {
+	var validationErr ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr
+	}
+
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		return []*FieldError{fieldErr}
+	}
+
+	return nil
+}
@@ -16,8 +16,30 @@ type GetMultipleResponse[T any] struct {
 	Next string `json:"__next,omitempty"`
 }
 
-type errorResponse struct {
-	Message string `json:"message" example:"internal server error"`
+// RequestIdHeader carries the id of the request, which the app sets on the
+// response before any handler runs.
+const RequestIdHeader string = "X-Request-ID"
+
+const problemContentType string = "application/problem+json"
+
+// FieldError is a problem with a single field of the request, named by its
+// JSON path, e.g. "address.postal_code".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem detail. Type defaults to "about:blank",
+// Title to the status text, Instance to the request path and RequestId to
+// the X-Request-ID of the response.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestId string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
 }
 
 func WithJson(w http.ResponseWriter, code int, data any) {
@@ -35,6 +57,38 @@ func WithJson(w http.ResponseWriter, code int, data any) {
 	w.Write(buffer.Bytes())
 }
 
-func Error(w http.ResponseWriter, code int, errorMessage string) {
-	WithJson(w, code, errorResponse{Message: errorMessage})
+// WithProblem answers r with problem as application/problem+json.
+func WithProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+
+	if problem.Instance == "" && r != nil {
+		problem.Instance = r.URL.Path
+	}
+
+	if problem.RequestId == "" {
+		problem.RequestId = w.Header().Get(RequestIdHeader)
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	err := json.NewEncoder(buffer).Encode(problem)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(buffer.Bytes())
+}
+
+// Error answers r with a problem of status code detailed by errorMessage.
+func Error(w http.ResponseWriter, r *http.Request, code int, errorMessage string) {
+	WithProblem(w, r, Problem{Status: code, Detail: errorMessage})
 }
@@ -0,0 +1,53 @@
+package responses
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set(RequestIdHeader, "abc")
+	r := httptest.NewRequest(http.MethodGet, "/api/customer/7?x=1", nil)
+
+	Error(w, r, http.StatusNotFound, "customer not found")
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+	problem := Problem{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+	assert.Equal(t, Problem{
+		Type:      "about:blank",
+		Title:     "Not Found",
+		Status:    http.StatusNotFound,
+		Detail:    "customer not found",
+		Instance:  "/api/customer/7",
+		RequestId: "abc",
+	}, problem)
+}
+
+func TestWithProblem(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	WithProblem(w, nil, Problem{
+		Type:   "https://example.com/problems/invalid",
+		Title:  "Invalid customer",
+		Status: http.StatusBadRequest,
+		Errors: []FieldError{{Field: "first_name", Message: "customer first name is required"}},
+	})
+
+	body := map[string]any{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, map[string]any{
+		"type":   "https://example.com/problems/invalid",
+		"title":  "Invalid customer",
+		"status": float64(http.StatusBadRequest),
+		"errors": []any{map[string]any{"field": "first_name", "message": "customer first name is required"}},
+	}, body, "empty members should be left out")
+}
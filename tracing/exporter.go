@@ -0,0 +1,249 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/mmiftahrzki/customer/config"
+	"github.com/mmiftahrzki/customer/logger"
+)
+
+const (
+	ExporterNone     string = ""
+	ExporterStdout   string = "stdout"
+	ExporterOTLPFile string = "otlp-file"
+)
+
+var log = logger.GetLogger().WithField("component", "tracing")
+
+// Exporter receives every sampled span once it has ended.
+type Exporter interface {
+	Export(span SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+type stdoutSpan struct {
+	TraceId    string         `json:"trace_id"`
+	SpanId     string         `json:"span_id"`
+	ParentId   string         `json:"parent_id,omitempty"`
+	Name       string         `json:"name"`
+	Kind       string         `json:"kind"`
+	Start      string         `json:"start"`
+	DurationMs float64        `json:"duration_ms"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+type stdoutExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewStdoutExporter writes one human readable JSON object per span to w.
+func NewStdoutExporter(w io.Writer) Exporter {
+	return &stdoutExporter{encoder: json.NewEncoder(w)}
+}
+
+func (e *stdoutExporter) Export(span SpanData) error {
+	out := stdoutSpan{
+		TraceId:    span.SpanContext.TraceID.String(),
+		SpanId:     span.SpanContext.SpanID.String(),
+		Name:       span.Name,
+		Kind:       span.Kind.String(),
+		Start:      span.Start.Format("2006-01-02T15:04:05.000000Z07:00"),
+		DurationMs: float64(span.End.Sub(span.Start).Microseconds()) / 1000,
+		Attributes: span.Attributes,
+		Error:      span.Error,
+	}
+
+	if span.Parent.IsValid() {
+		out.ParentId = span.Parent.String()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.encoder.Encode(out)
+}
+
+func (e *stdoutExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// otlpFileExporter appends spans to a file in the OTLP/JSON encoding, one
+// ExportTraceServiceRequest per line, the format the OpenTelemetry
+// Collector's file receiver and exporter use.
+type otlpFileExporter struct {
+	mu          sync.Mutex
+	file        *os.File
+	serviceName string
+}
+
+func NewOTLPFileExporter(path string, serviceName string) (Exporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &otlpFileExporter{file: file, serviceName: serviceName}, nil
+}
+
+type otlpValue map[string]any
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+func toOTLPValue(value any) otlpValue {
+	switch v := value.(type) {
+	case string:
+		return otlpValue{"stringValue": v}
+	case bool:
+		return otlpValue{"boolValue": v}
+	case int:
+		return otlpValue{"intValue": strconv.Itoa(v)}
+	case int64:
+		return otlpValue{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return otlpValue{"doubleValue": v}
+	default:
+		return otlpValue{"stringValue": fmt.Sprint(v)}
+	}
+}
+
+func toOTLPAttributes(attributes map[string]any) []otlpAttribute {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	out := make([]otlpAttribute, len(keys))
+	for i, key := range keys {
+		out[i] = otlpAttribute{Key: key, Value: toOTLPValue(attributes[key])}
+	}
+
+	return out
+}
+
+func (e *otlpFileExporter) Export(span SpanData) error {
+	// OTLP span kinds are offset by one: 1 internal, 2 server, 3 client.
+	out := otlpSpan{
+		TraceId:           span.SpanContext.TraceID.String(),
+		SpanId:            span.SpanContext.SpanID.String(),
+		Name:              span.Name,
+		Kind:              int(span.Kind) + 1,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		Attributes:        toOTLPAttributes(span.Attributes),
+		Status:            otlpStatus{Code: 1},
+	}
+
+	if span.Parent.IsValid() {
+		out.ParentSpanId = span.Parent.String()
+	}
+
+	if span.Error != "" {
+		out.Status = otlpStatus{Code: 2, Message: span.Error}
+	}
+
+	request := map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []otlpAttribute{{Key: "service.name", Value: toOTLPValue(e.serviceName)}},
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "github.com/mmiftahrzki/customer"},
+				"spans": []otlpSpan{out},
+			}},
+		}},
+	}
+
+	line, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, err = e.file.Write(append(line, '\n'))
+
+	return err
+}
+
+func (e *otlpFileExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.file.Close()
+}
+
+// Setup enables tracing as described by cfg and returns a function flushing
+// and closing the exporter. With no exporter configured tracing stays
+// disabled and the returned function does nothing.
+func Setup(cfg config.TracingConfig) (func(ctx context.Context) error, error) {
+	var exporter Exporter
+	var err error
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "customer"
+	}
+
+	sampleRatio := cfg.SampleRatio
+	if sampleRatio <= 0 {
+		sampleRatio = 1
+	}
+
+	switch cfg.Exporter {
+	case ExporterNone:
+		return func(ctx context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter = NewStdoutExporter(os.Stdout)
+	case ExporterOTLPFile:
+		if cfg.File == "" {
+			return nil, fmt.Errorf("tracing: the %s exporter needs a file", ExporterOTLPFile)
+		}
+
+		exporter, err = NewOTLPFileExporter(cfg.File, serviceName)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", cfg.Exporter)
+	}
+
+	SetExporter(exporter, sampleRatio)
+
+	return func(ctx context.Context) error {
+		SetExporter(nil, 0)
+
+		return exporter.Shutdown(ctx)
+	}, nil
+}
@@ -0,0 +1,27 @@
+package tracing
+
+import "github.com/sirupsen/logrus"
+
+// LogHook adds the trace and span ids of the current span to entries logged
+// with a context, e.g. log.WithContext(ctx).Error(err).
+type LogHook struct{}
+
+func (LogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (LogHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	sc := SpanFromContext(entry.Context).SpanContext()
+	if !sc.IsValid() {
+		return nil
+	}
+
+	entry.Data["trace_id"] = sc.TraceID.String()
+	entry.Data["span_id"] = sc.SpanID.String()
+
+	return nil
+}
@@ -0,0 +1,325 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+type SpanID [8]byte
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent renders sc as a W3C Trace Context traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent reads a W3C Trace Context traceparent header value. Fields
+// appended by versions newer than 00 are ignored, as the specification asks.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(parts) != 4) {
+		return sc, false
+	}
+
+	if _, err = hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+
+	if _, err = hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+
+	sc.Sampled = flags[0]&0x01 == 0x01
+
+	return sc, sc.IsValid()
+}
+
+type SpanKind int
+
+const (
+	KindInternal SpanKind = iota
+	KindServer
+	KindClient
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case KindServer:
+		return "server"
+	case KindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// SpanData is what exporters receive once a span has ended.
+type SpanData struct {
+	Name        string
+	Kind        SpanKind
+	SpanContext SpanContext
+	Parent      SpanID
+	Start       time.Time
+	End         time.Time
+	Attributes  map[string]any
+	Error       string
+}
+
+// Span is an operation being timed. A nil *Span, returned when tracing is
+// disabled, is valid and does nothing.
+type Span struct {
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+	tracer *tracer
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.data.SpanContext
+}
+
+// SetName renames the span, for when a better name is only known once the
+// operation has run, e.g. the route a request matched.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Name = name
+}
+
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Attributes[key] = value
+}
+
+// RecordError marks the span as failed. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Error = err.Error()
+}
+
+// End finishes the span and hands it to the exporter if it was sampled.
+// Calls after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+
+		return
+	}
+
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if data.SpanContext.Sampled {
+		s.tracer.export(data)
+	}
+}
+
+type contextKey int
+
+const (
+	spanContextKey contextKey = iota
+	remoteParentContextKey
+)
+
+// ContextWithSpan returns a copy of ctx carrying span as the current span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey, span)
+}
+
+// SpanFromContext returns the current span of ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey).(*Span)
+
+	return span
+}
+
+// ContextWithRemoteParent makes sc, usually read from an incoming
+// traceparent header, the parent of the next span started from ctx.
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteParentContextKey, sc)
+}
+
+type startOptions struct {
+	kind SpanKind
+}
+
+type StartOption func(*startOptions)
+
+func WithKind(kind SpanKind) StartOption {
+	return func(o *startOptions) {
+		o.kind = kind
+	}
+}
+
+// Start begins a span named name as a child of the span in ctx, or of a
+// remote parent, or as the root of a new trace. The span must be ended with
+// End. While tracing is disabled it returns ctx untouched and a nil span.
+func Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	t := current()
+	if t == nil {
+		return ctx, nil
+	}
+
+	options := startOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:       name,
+			Kind:       options.kind,
+			Start:      time.Now(),
+			Attributes: map[string]any{},
+		},
+	}
+
+	parent := SpanFromContext(ctx).SpanContext()
+	if !parent.IsValid() {
+		parent, _ = ctx.Value(remoteParentContextKey).(SpanContext)
+	}
+
+	if parent.IsValid() {
+		span.data.SpanContext.TraceID = parent.TraceID
+		span.data.SpanContext.Sampled = parent.Sampled
+		span.data.Parent = parent.SpanID
+	} else {
+		rand.Read(span.data.SpanContext.TraceID[:])
+		span.data.SpanContext.Sampled = t.sample()
+	}
+
+	rand.Read(span.data.SpanContext.SpanID[:])
+
+	return ContextWithSpan(ctx, span), span
+}
+
+type tracer struct {
+	exporter    Exporter
+	sampleRatio float64
+}
+
+func (t *tracer) sample() bool {
+	if t.sampleRatio >= 1 {
+		return true
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return false
+	}
+
+	return float64(n.Int64()) < t.sampleRatio*1_000_000
+}
+
+func (t *tracer) export(data SpanData) {
+	if err := t.exporter.Export(data); err != nil {
+		log.Error(err)
+	}
+}
+
+var (
+	mu     sync.RWMutex
+	global *tracer
+)
+
+func current() *tracer {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return global
+}
+
+// SetExporter enables tracing, sending sampled spans to exporter. A nil
+// exporter disables tracing.
+func SetExporter(exporter Exporter, sampleRatio float64) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if exporter == nil {
+		global = nil
+
+		return
+	}
+
+	global = &tracer{exporter: exporter, sampleRatio: sampleRatio}
+}
@@ -0,0 +1,156 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryExporter struct {
+	spans []SpanData
+}
+
+func (e *memoryExporter) Export(span SpanData) error {
+	e.spans = append(e.spans, span)
+
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if assert.True(t, ok) {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+		assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+		assert.True(t, sc.Sampled)
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+	}
+
+	_, ok = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future")
+	assert.True(t, ok, "fields added by later versions are ignored")
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01",
+	} {
+		_, ok = ParseTraceparent(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
+
+func TestStart(t *testing.T) {
+	exporter := &memoryExporter{}
+	SetExporter(exporter, 1)
+	defer SetExporter(nil, 0)
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx, parent := Start(ContextWithRemoteParent(context.Background(), remote), "parent", WithKind(KindServer))
+	_, child := Start(ctx, "child")
+	child.RecordError(errors.New("boom"))
+	child.End()
+	parent.End()
+	parent.End()
+
+	if !assert.Len(t, exporter.spans, 2) {
+		return
+	}
+
+	assert.Equal(t, "child", exporter.spans[0].Name)
+	assert.Equal(t, "boom", exporter.spans[0].Error)
+	assert.Equal(t, parent.SpanContext().SpanID, exporter.spans[0].Parent)
+	assert.Equal(t, remote.TraceID, exporter.spans[0].SpanContext.TraceID)
+	assert.Equal(t, remote.SpanID, exporter.spans[1].Parent)
+	assert.Equal(t, KindServer, exporter.spans[1].Kind)
+}
+
+func TestUnsampledTraceIsNotExported(t *testing.T) {
+	exporter := &memoryExporter{}
+	SetExporter(exporter, 1)
+	defer SetExporter(nil, 0)
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	ctx, span := Start(ContextWithRemoteParent(context.Background(), remote), "parent")
+	_, child := Start(ctx, "child")
+	child.End()
+	span.End()
+
+	assert.Empty(t, exporter.spans)
+	assert.Equal(t, remote.TraceID, child.SpanContext().TraceID)
+}
+
+func TestDisabled(t *testing.T) {
+	ctx := context.Background()
+
+	got, span := Start(ctx, "noop")
+	span.SetAttribute("key", "value")
+	span.End()
+
+	assert.Nil(t, span)
+	assert.Equal(t, ctx, got)
+}
+
+func TestLogHook(t *testing.T) {
+	SetExporter(&memoryExporter{}, 1)
+	defer SetExporter(nil, 0)
+
+	ctx, span := Start(context.Background(), "request")
+	defer span.End()
+
+	entry := logrus.NewEntry(logrus.New()).WithContext(ctx)
+	assert.Nil(t, LogHook{}.Fire(entry))
+	assert.Equal(t, span.SpanContext().TraceID.String(), entry.Data["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID.String(), entry.Data["span_id"])
+}
+
+func TestOTLPFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+
+	exporter, err := NewOTLPFileExporter(path, "customer")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	SetExporter(exporter, 1)
+	_, span := Start(context.Background(), "sql SELECT", WithKind(KindClient))
+	span.SetAttribute("db.system", "mysql")
+	span.End()
+	SetExporter(nil, 0)
+	assert.Nil(t, exporter.Shutdown(context.Background()))
+
+	content, err := os.ReadFile(path)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var request struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []otlpSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if !assert.Nil(t, json.Unmarshal(content, &request)) {
+		return
+	}
+
+	exported := request.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "sql SELECT", exported.Name)
+	assert.Equal(t, 3, exported.Kind)
+	assert.Equal(t, span.SpanContext().TraceID.String(), exported.TraceId)
+	assert.Equal(t, "db.system", exported.Attributes[0].Key)
+}
@@ -9,7 +9,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -88,7 +87,7 @@ func (h *handler) CreateUser(writer http.ResponseWriter, request *http.Request,
 	loc, _ := time.LoadLocation("Asia/Jakarta")
 	id := uuid.New()
 	now := time.Now().In(loc)
-	hmac_sha256 := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET_KEY")))
+	hmac_sha256 := hmac.New(sha256.New, secretKey)
 	hmac_sha256.Write([]byte(user.Password))
 
 	password_hash, err := bcrypt.GenerateFromPassword(hmac_sha256.Sum(nil), bcrypt.DefaultCost)
@@ -195,7 +194,7 @@ func (h *handler) ReadUser(writer http.ResponseWriter, request *http.Request, pa
 		return
 	}
 
-	hmac_sha256 := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET_KEY")))
+	hmac_sha256 := hmac.New(sha256.New, secretKey)
 	hmac_sha256.Write([]byte(user_login.Password))
 
 	sql_query := "SELECT password FROM user WHERE email=?;"
@@ -6,7 +6,6 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/google/uuid"
@@ -36,7 +35,7 @@ func (r *repo) InsertSingle(ctx context.Context, user User) error {
 	}
 	id := uuid.New()
 	now := time.Now().In(loc)
-	hmac_sha256 := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET_KEY")))
+	hmac_sha256 := hmac.New(sha256.New, secretKey)
 	hmac_sha256.Write([]byte(user.Password))
 
 	password, err := bcrypt.GenerateFromPassword(hmac_sha256.Sum(nil), 12)
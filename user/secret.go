@@ -0,0 +1,9 @@
+package user
+
+// secretKey is the pepper mixed into password hashes. It is the configured
+// auth.jwt_secret_key, which used to be read from JWT_SECRET_KEY directly.
+var secretKey []byte
+
+func SetSecretKey(key []byte) {
+	secretKey = key
+}